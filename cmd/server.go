@@ -5,8 +5,14 @@ Licensed under the MIT license, see LICENSE in the project root for details.
 package cmd
 
 import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gosnmp/gosnmp"
 	"github.com/spf13/cobra"
 	"github.com/tschaefer/rpinfo/server"
+	"github.com/tschaefer/rpinfo/server/snmp"
 )
 
 var serverCmd = &cobra.Command{
@@ -22,9 +28,63 @@ func init() {
 	serverCmd.Flags().BoolP("auth", "a", false, "Enable authentication")
 	serverCmd.Flags().StringP("token", "t", "", "Bearer Token for authentication")
 	serverCmd.Flags().BoolP("metrics", "m", false, "Enable Prometheus metrics")
+	serverCmd.Flags().BoolP("metrics-legacy", "L", false, "Expose the legacy metric names, e.g. rpi_clock_arm")
 	serverCmd.Flags().BoolP("redoc", "r", false, "Enable ReDoc API documentation")
 	serverCmd.Flags().StringP("log-format", "f", "structured", "Log format (structured, json)")
 	serverCmd.Flags().StringP("log-level", "l", "info", "Log level (debug, info, warn, error)")
+	serverCmd.Flags().StringP("backend", "b", "vcgencmd", "Command backend (vcgencmd, mailbox)")
+	serverCmd.Flags().DurationP("timeout", "T", 2*time.Second, "Timeout for a single command")
+	serverCmd.Flags().DurationP("cache-ttl", "c", 0, "Time to live of cached command results (0 disables the cache)")
+	serverCmd.Flags().StringToStringP("cache-ttl-command", "C", nil, "Time to live per command, e.g. get_config=1m")
+	serverCmd.Flags().DurationP("sample-interval", "s", 0, "Interval of the background sampler, required by history, stream and websocket (0 disables the sampler)")
+	serverCmd.Flags().IntP("history-size", "S", 360, "Number of samples kept in the history")
+	serverCmd.Flags().IntP("max-subscribers", "w", 8, "Maximum number of concurrent websocket subscribers (requires the sampler)")
+	serverCmd.Flags().DurationP("watch-interval", "W", 0, "Interval of the throttled watcher, e.g. 100ms (0 disables the events)")
+	serverCmd.Flags().StringP("alert-rules", "A", "", "Alert rules file (requires the sampler)")
+	serverCmd.Flags().String("remote-write-url", "", "Prometheus remote write URL to push the metrics to")
+	serverCmd.Flags().Duration("remote-write-interval", 30*time.Second, "Interval of the remote write push")
+	serverCmd.Flags().String("remote-write-token", "", "Bearer token for the remote write receiver")
+	serverCmd.Flags().String("remote-write-username", "", "Basic auth username for the remote write receiver")
+	serverCmd.Flags().String("remote-write-password", "", "Basic auth password for the remote write receiver")
+	serverCmd.Flags().Int("remote-write-queue-size", 120, "Number of pushes kept while the receiver is unreachable")
+	serverCmd.Flags().String("influx-measurement", "rpinfo", "Measurement name of the InfluxDB line protocol")
+	serverCmd.Flags().StringToString("influx-tag", nil, "Additional tag of the InfluxDB line protocol, e.g. site=lab")
+	serverCmd.Flags().String("influx-url", "", "InfluxDB v2 URL to push the readings to")
+	serverCmd.Flags().String("influx-org", "", "InfluxDB organization")
+	serverCmd.Flags().String("influx-bucket", "", "InfluxDB bucket")
+	serverCmd.Flags().String("influx-token", "", "InfluxDB API token")
+	serverCmd.Flags().Duration("influx-interval", 10*time.Second, "Interval of the InfluxDB readings")
+	serverCmd.Flags().Int("influx-batch-size", 6, "Number of readings per InfluxDB write")
+	serverCmd.Flags().String("mqtt-broker", "", "MQTT broker to publish the readings to, e.g. ssl://broker:8883")
+	serverCmd.Flags().String("mqtt-client-id", "", "MQTT client id (default rpinfo-<hostname>)")
+	serverCmd.Flags().String("mqtt-username", "", "MQTT username")
+	serverCmd.Flags().String("mqtt-password", "", "MQTT password")
+	serverCmd.Flags().String("mqtt-topic", "", "MQTT topic prefix of the readings (default rpinfo/<hostname>)")
+	serverCmd.Flags().String("mqtt-discovery-prefix", "homeassistant", "Home Assistant MQTT discovery prefix (empty disables the discovery)")
+	serverCmd.Flags().Bool("mqtt-retain", false, "Retain the published readings")
+	serverCmd.Flags().Duration("mqtt-interval", 30*time.Second, "Interval of the MQTT readings")
+	serverCmd.Flags().String("mqtt-ca-file", "", "CA certificate file of the MQTT broker")
+	serverCmd.Flags().String("mqtt-cert-file", "", "Client certificate file for the MQTT broker")
+	serverCmd.Flags().String("mqtt-key-file", "", "Client key file for the MQTT broker")
+	serverCmd.Flags().String("otlp-endpoint", "", "OTLP endpoint to export the metrics to, e.g. http://collector:4318/v1/metrics")
+	serverCmd.Flags().String("otlp-protocol", "http", "OTLP protocol, http or grpc")
+	serverCmd.Flags().StringToString("otlp-header", nil, "Additional header of the OTLP export, e.g. Authorization=Bearer token")
+	serverCmd.Flags().Duration("otlp-interval", 60*time.Second, "Interval of the OTLP export")
+	serverCmd.Flags().String("otlp-ca-file", "", "CA certificate file of the OTLP endpoint")
+	serverCmd.Flags().String("otlp-cert-file", "", "Client certificate file for the OTLP endpoint")
+	serverCmd.Flags().String("otlp-key-file", "", "Client key file for the OTLP endpoint")
+	serverCmd.Flags().String("graphite-address", "", "Graphite or StatsD address to emit the readings to, e.g. graphite:2003")
+	serverCmd.Flags().String("graphite-network", "tcp", "Network of the Graphite emitter, tcp or udp")
+	serverCmd.Flags().String("graphite-format", "graphite", "Format of the Graphite emitter, graphite plaintext or statsd gauges")
+	serverCmd.Flags().String("graphite-prefix", "rpinfo.{host}", "Metric path prefix, {host} is replaced by the hostname")
+	serverCmd.Flags().Duration("graphite-interval", 60*time.Second, "Interval of the Graphite readings")
+	serverCmd.Flags().String("snmp-address", "", "Address of the SNMP agent, e.g. :161")
+	serverCmd.Flags().String("snmp-community", "", "SNMPv2c community (empty disables SNMPv2c)")
+	serverCmd.Flags().String("snmp-user", "", "SNMPv3 user (empty disables SNMPv3)")
+	serverCmd.Flags().String("snmp-auth-protocol", "SHA256", "SNMPv3 authentication protocol, e.g. SHA or SHA256")
+	serverCmd.Flags().String("snmp-auth-passphrase", "", "SNMPv3 authentication passphrase")
+	serverCmd.Flags().String("snmp-priv-protocol", "AES", "SNMPv3 privacy protocol, e.g. AES or NoPriv")
+	serverCmd.Flags().String("snmp-priv-passphrase", "", "SNMPv3 privacy passphrase")
 
 	rootCmd.AddCommand(serverCmd)
 }
@@ -37,11 +97,189 @@ func RunServerCmd(cmd *cobra.Command, args []string) error {
 	config.Auth, _ = cmd.Flags().GetBool("auth")
 	config.Token, _ = cmd.Flags().GetString("token")
 	config.Metrics, _ = cmd.Flags().GetBool("metrics")
+	config.MetricsLegacy, _ = cmd.Flags().GetBool("metrics-legacy")
 	config.Redoc, _ = cmd.Flags().GetBool("redoc")
 	config.LogFormat, _ = cmd.Flags().GetString("log-format")
 	config.LogLevel, _ = cmd.Flags().GetString("log-level")
+	config.Backend, _ = cmd.Flags().GetString("backend")
+	config.Timeout, _ = cmd.Flags().GetDuration("timeout")
+	config.CacheTTL, _ = cmd.Flags().GetDuration("cache-ttl")
+
+	ttls, _ := cmd.Flags().GetStringToString("cache-ttl-command")
+	config.CacheTTLs = make(map[string]time.Duration)
+	for command, value := range ttls {
+		ttl, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("invalid cache ttl for %s: %v", command, err)
+		}
+		config.CacheTTLs[command] = ttl
+	}
+	config.SampleInterval, _ = cmd.Flags().GetDuration("sample-interval")
+	config.HistorySize, _ = cmd.Flags().GetInt("history-size")
+	if config.HistorySize <= 0 {
+		return fmt.Errorf("invalid history size: %d", config.HistorySize)
+	}
+	config.MaxSubscribers, _ = cmd.Flags().GetInt("max-subscribers")
+	if config.MaxSubscribers <= 0 {
+		return fmt.Errorf("invalid max subscribers: %d", config.MaxSubscribers)
+	}
+	if cmd.Flags().Changed("max-subscribers") && config.SampleInterval <= 0 {
+		return fmt.Errorf("max subscribers require the sampler, set --sample-interval")
+	}
+	config.WatchInterval, _ = cmd.Flags().GetDuration("watch-interval")
+	config.AlertRules, _ = cmd.Flags().GetString("alert-rules")
+	if config.AlertRules != "" && config.SampleInterval <= 0 {
+		return fmt.Errorf("alert rules require the sampler, set --sample-interval")
+	}
+	config.RemoteWriteURL, _ = cmd.Flags().GetString("remote-write-url")
+	config.RemoteWriteInterval, _ = cmd.Flags().GetDuration("remote-write-interval")
+	config.RemoteWriteToken, _ = cmd.Flags().GetString("remote-write-token")
+	config.RemoteWriteUsername, _ = cmd.Flags().GetString("remote-write-username")
+	config.RemoteWritePassword, _ = cmd.Flags().GetString("remote-write-password")
+	config.RemoteWriteQueueSize, _ = cmd.Flags().GetInt("remote-write-queue-size")
+	if config.RemoteWriteURL != "" {
+		if !strings.HasPrefix(config.RemoteWriteURL, "http://") && !strings.HasPrefix(config.RemoteWriteURL, "https://") {
+			return fmt.Errorf("invalid remote write url: %s", config.RemoteWriteURL)
+		}
+		if config.RemoteWriteInterval <= 0 {
+			return fmt.Errorf("invalid remote write interval: %s", config.RemoteWriteInterval)
+		}
+		if config.RemoteWriteQueueSize <= 0 {
+			return fmt.Errorf("invalid remote write queue size: %d", config.RemoteWriteQueueSize)
+		}
+		if config.RemoteWriteToken != "" && config.RemoteWriteUsername != "" {
+			return fmt.Errorf("remote write token and username are mutually exclusive")
+		}
+	}
+	config.InfluxMeasurement, _ = cmd.Flags().GetString("influx-measurement")
+	if config.InfluxMeasurement == "" {
+		return fmt.Errorf("invalid influx measurement: must not be empty")
+	}
+	config.InfluxTags, _ = cmd.Flags().GetStringToString("influx-tag")
+	config.InfluxURL, _ = cmd.Flags().GetString("influx-url")
+	config.InfluxOrg, _ = cmd.Flags().GetString("influx-org")
+	config.InfluxBucket, _ = cmd.Flags().GetString("influx-bucket")
+	config.InfluxToken, _ = cmd.Flags().GetString("influx-token")
+	config.InfluxInterval, _ = cmd.Flags().GetDuration("influx-interval")
+	config.InfluxBatchSize, _ = cmd.Flags().GetInt("influx-batch-size")
+	if config.InfluxURL != "" {
+		if !strings.HasPrefix(config.InfluxURL, "http://") && !strings.HasPrefix(config.InfluxURL, "https://") {
+			return fmt.Errorf("invalid influx url: %s", config.InfluxURL)
+		}
+		if config.InfluxBucket == "" {
+			return fmt.Errorf("influx push requires a bucket, set --influx-bucket")
+		}
+		if config.InfluxInterval <= 0 {
+			return fmt.Errorf("invalid influx interval: %s", config.InfluxInterval)
+		}
+		if config.InfluxBatchSize <= 0 {
+			return fmt.Errorf("invalid influx batch size: %d", config.InfluxBatchSize)
+		}
+	}
+	config.MQTTBroker, _ = cmd.Flags().GetString("mqtt-broker")
+	config.MQTTClientID, _ = cmd.Flags().GetString("mqtt-client-id")
+	config.MQTTUsername, _ = cmd.Flags().GetString("mqtt-username")
+	config.MQTTPassword, _ = cmd.Flags().GetString("mqtt-password")
+	config.MQTTTopic, _ = cmd.Flags().GetString("mqtt-topic")
+	config.MQTTDiscovery, _ = cmd.Flags().GetString("mqtt-discovery-prefix")
+	config.MQTTRetain, _ = cmd.Flags().GetBool("mqtt-retain")
+	config.MQTTInterval, _ = cmd.Flags().GetDuration("mqtt-interval")
+	config.MQTTCAFile, _ = cmd.Flags().GetString("mqtt-ca-file")
+	config.MQTTCertFile, _ = cmd.Flags().GetString("mqtt-cert-file")
+	config.MQTTKeyFile, _ = cmd.Flags().GetString("mqtt-key-file")
+	if config.MQTTBroker != "" {
+		if config.MQTTInterval <= 0 {
+			return fmt.Errorf("invalid mqtt interval: %s", config.MQTTInterval)
+		}
+		if (config.MQTTCertFile == "") != (config.MQTTKeyFile == "") {
+			return fmt.Errorf("mqtt client certificate requires both --mqtt-cert-file and --mqtt-key-file")
+		}
+	}
+	config.OTLPEndpoint, _ = cmd.Flags().GetString("otlp-endpoint")
+	config.OTLPProtocol, _ = cmd.Flags().GetString("otlp-protocol")
+	config.OTLPHeaders, _ = cmd.Flags().GetStringToString("otlp-header")
+	config.OTLPInterval, _ = cmd.Flags().GetDuration("otlp-interval")
+	config.OTLPCAFile, _ = cmd.Flags().GetString("otlp-ca-file")
+	config.OTLPCertFile, _ = cmd.Flags().GetString("otlp-cert-file")
+	config.OTLPKeyFile, _ = cmd.Flags().GetString("otlp-key-file")
+	if config.OTLPEndpoint != "" {
+		if !strings.HasPrefix(config.OTLPEndpoint, "http://") && !strings.HasPrefix(config.OTLPEndpoint, "https://") {
+			return fmt.Errorf("invalid otlp endpoint: %s", config.OTLPEndpoint)
+		}
+		if config.OTLPProtocol != "http" && config.OTLPProtocol != "grpc" {
+			return fmt.Errorf("invalid otlp protocol: %s", config.OTLPProtocol)
+		}
+		if config.OTLPInterval <= 0 {
+			return fmt.Errorf("invalid otlp interval: %s", config.OTLPInterval)
+		}
+		if (config.OTLPCertFile == "") != (config.OTLPKeyFile == "") {
+			return fmt.Errorf("otlp client certificate requires both --otlp-cert-file and --otlp-key-file")
+		}
+	}
+	config.GraphiteAddress, _ = cmd.Flags().GetString("graphite-address")
+	config.GraphiteNetwork, _ = cmd.Flags().GetString("graphite-network")
+	config.GraphiteFormat, _ = cmd.Flags().GetString("graphite-format")
+	config.GraphitePrefix, _ = cmd.Flags().GetString("graphite-prefix")
+	config.GraphiteInterval, _ = cmd.Flags().GetDuration("graphite-interval")
+	if config.GraphiteAddress != "" {
+		if config.GraphiteNetwork != "tcp" && config.GraphiteNetwork != "udp" {
+			return fmt.Errorf("invalid graphite network: %s", config.GraphiteNetwork)
+		}
+		if config.GraphiteFormat != "graphite" && config.GraphiteFormat != "statsd" {
+			return fmt.Errorf("invalid graphite format: %s", config.GraphiteFormat)
+		}
+		if config.GraphitePrefix == "" {
+			return fmt.Errorf("invalid graphite prefix: must not be empty")
+		}
+		if config.GraphiteInterval <= 0 {
+			return fmt.Errorf("invalid graphite interval: %s", config.GraphiteInterval)
+		}
+	}
+	config.SNMPAddress, _ = cmd.Flags().GetString("snmp-address")
+	config.SNMPCommunity, _ = cmd.Flags().GetString("snmp-community")
+	config.SNMPUser, _ = cmd.Flags().GetString("snmp-user")
+	config.SNMPAuthProtocol, _ = cmd.Flags().GetString("snmp-auth-protocol")
+	config.SNMPAuthPassphrase, _ = cmd.Flags().GetString("snmp-auth-passphrase")
+	config.SNMPPrivProtocol, _ = cmd.Flags().GetString("snmp-priv-protocol")
+	config.SNMPPrivPassphrase, _ = cmd.Flags().GetString("snmp-priv-passphrase")
+	if config.SNMPAddress != "" {
+		if config.SNMPCommunity == "" && config.SNMPUser == "" {
+			return fmt.Errorf("snmp agent requires --snmp-community or --snmp-user")
+		}
+		if err := validateSNMPUser(config); err != nil {
+			return err
+		}
+	}
 
 	server.Run(config)
 
 	return nil
 }
+
+// validateSNMPUser checks the protocols and passphrases of the SNMPv3 user,
+// passphrases require at least 8 characters, see RFC 3414.
+func validateSNMPUser(config server.Config) error {
+	if config.SNMPUser == "" {
+		return nil
+	}
+
+	auth, err := snmp.ParseAuthProtocol(config.SNMPAuthProtocol)
+	if err != nil {
+		return err
+	}
+	priv, err := snmp.ParsePrivProtocol(config.SNMPPrivProtocol)
+	if err != nil {
+		return err
+	}
+	if auth == gosnmp.NoAuth && priv != gosnmp.NoPriv {
+		return fmt.Errorf("snmp privacy requires an authentication protocol")
+	}
+	if auth != gosnmp.NoAuth && len(config.SNMPAuthPassphrase) < 8 {
+		return fmt.Errorf("snmp auth passphrase requires at least 8 characters")
+	}
+	if priv != gosnmp.NoPriv && len(config.SNMPPrivPassphrase) < 8 {
+		return fmt.Errorf("snmp priv passphrase requires at least 8 characters")
+	}
+
+	return nil
+}
@@ -5,7 +5,10 @@ Licensed under the MIT license.
 package cmd
 
 import (
+	"fmt"
+
 	"github.com/spf13/cobra"
+	"github.com/tschaefer/rpinfo/server/board"
 	"github.com/tschaefer/rpinfo/version"
 )
 
@@ -15,6 +18,9 @@ var versionCmd = &cobra.Command{
 	Long:  "Display version information",
 	Run: func(cmd *cobra.Command, args []string) {
 		version.Print()
+		if rpi := board.Read(); rpi.Model != "" {
+			fmt.Printf("Board:   %s\n", rpi)
+		}
 	},
 }
 
@@ -0,0 +1,205 @@
+/*
+Copyright (c) 2025 Tobias Schäfer. All rights reserved.
+Licensed under the MIT license, see LICENSE in the project root for details.
+*/
+package alert
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/tschaefer/rpinfo/server/sampler"
+)
+
+const (
+	StateInactive = "inactive"
+	StatePending  = "pending"
+	StateFiring   = "firing"
+)
+
+// Alert is the state of a rule
+type Alert struct {
+	Rule       string     `json:"rule"`
+	Metric     string     `json:"metric"`
+	Comparator string     `json:"comparator"`
+	Threshold  float64    `json:"threshold"`
+	For        string     `json:"for"`
+	State      string     `json:"state"`
+	Value      float64    `json:"value"`
+	ActiveAt   *time.Time `json:"active_at,omitempty"`
+	FiredAt    *time.Time `json:"fired_at,omitempty"`
+	ResolvedAt *time.Time `json:"resolved_at,omitempty"`
+}
+
+// Engine evaluates the rules against every new snapshot of the sampler and
+// notifies about firing and resolved alerts.
+type Engine struct {
+	Rules    []Rule
+	Sampler  *sampler.Sampler
+	Notifier *Notifier
+
+	mu        sync.RWMutex
+	alerts    map[string]*Alert
+	evaluated time.Time
+}
+
+// queueSize is the number of notifications of a rule kept while its
+// webhooks are slow or unreachable.
+const queueSize = 64
+
+// Run evaluates at the sampler interval until the context is done.
+func (e *Engine) Run(ctx context.Context) {
+	ticker := time.NewTicker(e.Sampler.Interval)
+	defer ticker.Stop()
+
+	var notifications *queues
+	if e.Notifier != nil {
+		notifications = newQueues(ctx, e.Notifier, e.Rules)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		snapshot, ok := e.Sampler.Latest()
+		if !ok || !snapshot.Time.After(e.evaluated) {
+			continue
+		}
+		e.evaluated = snapshot.Time
+
+		for _, alert := range e.Evaluate(snapshot) {
+			if alert.State == StateFiring {
+				slog.Warn(fmt.Sprintf("Alert %s firing: %s %s %v, value %v", alert.Rule, alert.Metric, alert.Comparator, alert.Threshold, alert.Value))
+			} else {
+				slog.Info(fmt.Sprintf("Alert %s resolved: %s, value %v", alert.Rule, alert.Metric, alert.Value))
+			}
+			if notifications != nil {
+				notifications.push(alert)
+			}
+		}
+	}
+}
+
+// Evaluate updates the alert states and returns the alerts which started
+// firing or got resolved.
+func (e *Engine) Evaluate(snapshot sampler.Snapshot) []Alert {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.alerts == nil {
+		e.alerts = make(map[string]*Alert)
+	}
+
+	var changed []Alert
+	for _, rule := range e.Rules {
+		alert, ok := e.alerts[rule.Name]
+		if !ok {
+			alert = &Alert{
+				Rule:       rule.Name,
+				Metric:     rule.Metric,
+				Comparator: rule.Comparator,
+				Threshold:  rule.Threshold,
+				For:        rule.For.String(),
+				State:      StateInactive,
+			}
+			e.alerts[rule.Name] = alert
+		}
+
+		value, ok := snapshot.Value(rule.Metric)
+		if !ok {
+			continue
+		}
+		alert.Value = value
+		now := snapshot.Time
+
+		if !rule.Match(value) {
+			resolved := alert.State == StateFiring
+			alert.State = StateInactive
+			alert.ActiveAt = nil
+			if resolved {
+				alert.ResolvedAt = &now
+				changed = append(changed, *alert)
+			}
+			continue
+		}
+
+		switch alert.State {
+		case StateInactive:
+			alert.State = StatePending
+			alert.ActiveAt = &now
+			alert.FiredAt = nil
+			alert.ResolvedAt = nil
+		case StatePending:
+		case StateFiring:
+			continue
+		}
+
+		if now.Sub(*alert.ActiveAt) >= rule.For {
+			alert.State = StateFiring
+			alert.FiredAt = &now
+			changed = append(changed, *alert)
+		}
+	}
+
+	return changed
+}
+
+// Alerts returns the state of every rule in the order of the rules file.
+func (e *Engine) Alerts() []Alert {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	alerts := []Alert{}
+	for _, rule := range e.Rules {
+		if alert, ok := e.alerts[rule.Name]; ok {
+			alerts = append(alerts, *alert)
+		}
+	}
+
+	return alerts
+}
+
+// queues delivers the notifications of every rule one after another, so a
+// resolved alert never overtakes its firing one.
+type queues struct {
+	rules map[string]chan Alert
+}
+
+func newQueues(ctx context.Context, notifier *Notifier, rules []Rule) *queues {
+	q := &queues{rules: make(map[string]chan Alert)}
+	for _, rule := range rules {
+		queue := make(chan Alert, queueSize)
+		q.rules[rule.Name] = queue
+		go func() {
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case alert := <-queue:
+					notifier.Notify(ctx, alert)
+				}
+			}
+		}()
+	}
+
+	return q
+}
+
+func (q *queues) push(alert Alert) {
+	queue, ok := q.rules[alert.Rule]
+	if !ok {
+		return
+	}
+
+	select {
+	case queue <- alert:
+	default:
+		slog.Error(fmt.Sprintf("alert error: %s queue full, dropping %s notification", alert.Rule, alert.State))
+	}
+}
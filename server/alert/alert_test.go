@@ -0,0 +1,105 @@
+/*
+Copyright (c) 2025 Tobias Schäfer. All rights reserved.
+Licensed under the MIT license, see LICENSE in the project root for details.
+*/
+package alert
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tschaefer/rpinfo/server/sampler"
+	"github.com/tschaefer/rpinfo/vcgencmd"
+)
+
+var epoch = time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func snapshotAt(seconds int, temp float64) sampler.Snapshot {
+	return sampler.Snapshot{
+		Time:        epoch.Add(time.Duration(seconds) * time.Second),
+		Temperature: vcgencmd.Temperature(temp),
+	}
+}
+
+func Test_EvaluateFiresAfterDuration(t *testing.T) {
+	e := &Engine{Rules: []Rule{{Name: "hot", Metric: "temp", Comparator: ">", Threshold: 80, For: 10 * time.Second}}}
+
+	assert.Empty(t, e.Evaluate(snapshotAt(0, 70)))
+	assert.Equal(t, StateInactive, e.Alerts()[0].State)
+
+	assert.Empty(t, e.Evaluate(snapshotAt(5, 81)))
+	assert.Equal(t, StatePending, e.Alerts()[0].State)
+
+	assert.Empty(t, e.Evaluate(snapshotAt(10, 82)))
+	assert.Equal(t, StatePending, e.Alerts()[0].State)
+
+	changed := e.Evaluate(snapshotAt(15, 83))
+	assert.Len(t, changed, 1)
+	assert.Equal(t, StateFiring, changed[0].State)
+	assert.Equal(t, 83.0, changed[0].Value)
+	assert.Equal(t, epoch.Add(5*time.Second), *changed[0].ActiveAt)
+	assert.Equal(t, epoch.Add(15*time.Second), *changed[0].FiredAt)
+
+	assert.Empty(t, e.Evaluate(snapshotAt(20, 84)))
+	assert.Equal(t, StateFiring, e.Alerts()[0].State)
+
+	changed = e.Evaluate(snapshotAt(25, 75))
+	assert.Len(t, changed, 1)
+	assert.Equal(t, StateInactive, changed[0].State)
+	assert.Equal(t, epoch.Add(25*time.Second), *changed[0].ResolvedAt)
+}
+
+func Test_EvaluateResetsPendingAlert(t *testing.T) {
+	e := &Engine{Rules: []Rule{{Name: "hot", Metric: "temp", Comparator: ">", Threshold: 80, For: 10 * time.Second}}}
+
+	assert.Empty(t, e.Evaluate(snapshotAt(0, 81)))
+	assert.Empty(t, e.Evaluate(snapshotAt(5, 79)))
+	assert.Equal(t, StateInactive, e.Alerts()[0].State)
+	assert.Empty(t, e.Evaluate(snapshotAt(10, 81)))
+	assert.Equal(t, StatePending, e.Alerts()[0].State)
+	assert.Equal(t, epoch.Add(10*time.Second), *e.Alerts()[0].ActiveAt)
+}
+
+func Test_EvaluateFiresImmediatelyWithoutDuration(t *testing.T) {
+	e := &Engine{Rules: []Rule{{Name: "undervoltage", Metric: "throttled.under_voltage", Comparator: "==", Threshold: 1}}}
+
+	changed := e.Evaluate(sampler.Snapshot{Time: epoch, Throttled: vcgencmd.UnderVoltage})
+	assert.Len(t, changed, 1)
+	assert.Equal(t, StateFiring, changed[0].State)
+}
+
+func Test_QueuesDeliverNotificationsOfARuleInOrder(t *testing.T) {
+	var mu sync.Mutex
+	var statuses []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&payload)
+		if payload["status"] == "firing" {
+			time.Sleep(50 * time.Millisecond)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		statuses = append(statuses, payload["status"].(string))
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	q := newQueues(ctx, NewNotifier([]string{server.URL}), []Rule{{Name: "hot"}})
+	q.push(Alert{Rule: "hot", State: StateFiring})
+	q.push(Alert{Rule: "hot", State: StateInactive})
+
+	assert.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(statuses) == 2
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, []string{"firing", "resolved"}, statuses)
+}
@@ -0,0 +1,99 @@
+/*
+Copyright (c) 2025 Tobias Schäfer. All rights reserved.
+Licensed under the MIT license, see LICENSE in the project root for details.
+*/
+package alert
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/tschaefer/rpinfo/server/sampler"
+	"gopkg.in/yaml.v3"
+)
+
+// Config is the content of the rules file
+type Config struct {
+	Rules    []Rule   `yaml:"rules"`
+	Webhooks []string `yaml:"webhooks"`
+}
+
+// Rule fires once the comparison of a reading against the threshold holds
+// for the duration.
+type Rule struct {
+	Name       string        `yaml:"name" json:"name"`
+	Metric     string        `yaml:"metric" json:"metric"`
+	Comparator string        `yaml:"comparator" json:"comparator"`
+	Threshold  float64       `yaml:"threshold" json:"threshold"`
+	For        time.Duration `yaml:"for" json:"for"`
+}
+
+var comparators = map[string]func(a, b float64) bool{
+	">":  func(a, b float64) bool { return a > b },
+	">=": func(a, b float64) bool { return a >= b },
+	"<":  func(a, b float64) bool { return a < b },
+	"<=": func(a, b float64) bool { return a <= b },
+	"==": func(a, b float64) bool { return a == b },
+	"!=": func(a, b float64) bool { return a != b },
+}
+
+// Match reports whether the value satisfies the rule condition.
+func (r Rule) Match(value float64) bool {
+	return comparators[r.Comparator](value, r.Threshold)
+}
+
+// Load reads and validates a rules file, e.g.
+//
+//	rules:
+//	  - name: hot
+//	    metric: temp
+//	    comparator: ">"
+//	    threshold: 80
+//	    for: 1m
+//	webhooks:
+//	  - https://example.org/hook
+func Load(path string) (Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, err
+	}
+
+	var config Config
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return Config{}, fmt.Errorf("invalid rules file: %v", err)
+	}
+
+	return config, config.validate()
+}
+
+func (c Config) validate() error {
+	names := make(map[string]bool)
+	for _, rule := range c.Rules {
+		if rule.Name == "" || names[rule.Name] {
+			return fmt.Errorf("invalid rule name: %q", rule.Name)
+		}
+		names[rule.Name] = true
+
+		if !slices.Contains(sampler.Metrics(), rule.Metric) {
+			return fmt.Errorf("invalid metric of rule %s: %q", rule.Name, rule.Metric)
+		}
+		if _, ok := comparators[rule.Comparator]; !ok {
+			return fmt.Errorf("invalid comparator of rule %s: %q", rule.Name, rule.Comparator)
+		}
+		if rule.For < 0 {
+			return fmt.Errorf("invalid for of rule %s: %s", rule.Name, rule.For)
+		}
+	}
+
+	for _, webhook := range c.Webhooks {
+		u, err := url.Parse(webhook)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			return fmt.Errorf("invalid webhook: %q", webhook)
+		}
+	}
+
+	return nil
+}
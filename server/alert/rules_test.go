@@ -0,0 +1,93 @@
+/*
+Copyright (c) 2025 Tobias Schäfer. All rights reserved.
+Licensed under the MIT license, see LICENSE in the project root for details.
+*/
+package alert
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeRules(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "rules.yml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("failed to write rules: %v", err)
+	}
+
+	return path
+}
+
+func Test_LoadReturnsConfig(t *testing.T) {
+	path := writeRules(t, `
+rules:
+  - name: hot
+    metric: temp
+    comparator: ">"
+    threshold: 80
+    for: 1m
+  - name: undervoltage
+    metric: throttled.under_voltage
+    comparator: "=="
+    threshold: 1
+webhooks:
+  - https://example.org/hook
+`)
+
+	config, err := Load(path)
+	assert.Nil(t, err)
+	assert.Equal(t, Config{
+		Rules: []Rule{
+			{Name: "hot", Metric: "temp", Comparator: ">", Threshold: 80, For: time.Minute},
+			{Name: "undervoltage", Metric: "throttled.under_voltage", Comparator: "==", Threshold: 1},
+		},
+		Webhooks: []string{"https://example.org/hook"},
+	}, config)
+}
+
+func Test_LoadReturnsErrorIfRulesAreInvalid(t *testing.T) {
+	tests := map[string]string{
+		"rules:\n  - metric: temp\n    comparator: \">\"\n":                            `invalid rule name: ""`,
+		"rules:\n  - name: a\n    metric: humidity\n    comparator: \">\"\n":           `invalid metric of rule a: "humidity"`,
+		"rules:\n  - name: a\n    metric: temp\n    comparator: \"=~\"\n":              `invalid comparator of rule a: "=~"`,
+		"rules:\n  - name: a\n    metric: temp\n    comparator: \">\"\n    for: -1m\n": `invalid for of rule a: -1m0s`,
+		"webhooks:\n  - ftp://example.org\n":                                           `invalid webhook: "ftp://example.org"`,
+	}
+
+	for content, expected := range tests {
+		_, err := Load(writeRules(t, content))
+		if assert.NotNil(t, err) {
+			assert.Equal(t, expected, err.Error())
+		}
+	}
+
+	_, err := Load(writeRules(t, "rules: {"))
+	assert.NotNil(t, err)
+
+	_, err = Load(filepath.Join(t.TempDir(), "missing.yml"))
+	assert.NotNil(t, err)
+}
+
+func Test_RuleMatchComparesThreshold(t *testing.T) {
+	tests := []struct {
+		comparator string
+		value      float64
+		expected   bool
+	}{
+		{">", 81, true}, {">", 80, false},
+		{">=", 80, true}, {">=", 79, false},
+		{"<", 79, true}, {"<", 80, false},
+		{"<=", 80, true}, {"<=", 81, false},
+		{"==", 80, true}, {"==", 81, false},
+		{"!=", 81, true}, {"!=", 80, false},
+	}
+
+	for _, test := range tests {
+		rule := Rule{Comparator: test.comparator, Threshold: 80}
+		assert.Equal(t, test.expected, rule.Match(test.value), "%v %s 80", test.value, test.comparator)
+	}
+}
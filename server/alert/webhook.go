@@ -0,0 +1,125 @@
+/*
+Copyright (c) 2025 Tobias Schäfer. All rights reserved.
+Licensed under the MIT license, see LICENSE in the project root for details.
+*/
+package alert
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/tschaefer/rpinfo/version"
+)
+
+// Notifier posts alerts as JSON to webhooks. A failed delivery is retried
+// with exponential backoff.
+type Notifier struct {
+	Webhooks []string
+	Client   *http.Client
+	Retries  int
+	Backoff  time.Duration
+}
+
+func NewNotifier(webhooks []string) *Notifier {
+	return &Notifier{
+		Webhooks: webhooks,
+		Client:   &http.Client{Timeout: 10 * time.Second},
+		Retries:  5,
+		Backoff:  time.Second,
+	}
+}
+
+// Status is either firing or resolved
+type payload struct {
+	Status  string `json:"status"`
+	Host    string `json:"host"`
+	Version string `json:"version"`
+	Alert
+}
+
+// Notify delivers the alert to all webhooks and blocks until every delivery
+// succeeded or ran out of retries.
+func (n *Notifier) Notify(ctx context.Context, alert Alert) {
+	status := "resolved"
+	if alert.State == StateFiring {
+		status = "firing"
+	}
+
+	body, err := json.Marshal(payload{Status: status, Host: hostname(), Version: version.Release(), Alert: alert})
+	if err != nil {
+		slog.Error(fmt.Sprintf("alert error: %v", err))
+		return
+	}
+
+	done := make(chan struct{})
+	for _, webhook := range n.Webhooks {
+		go func() {
+			defer func() { done <- struct{}{} }()
+			if err := n.deliver(ctx, webhook, body); err != nil {
+				slog.Error(fmt.Sprintf("alert error: %s %s to %s: %v", alert.Rule, status, webhook, err))
+			}
+		}()
+	}
+	for range n.Webhooks {
+		<-done
+	}
+}
+
+func (n *Notifier) deliver(ctx context.Context, webhook string, body []byte) error {
+	backoff := n.Backoff
+
+	var err error
+	for attempt := 0; attempt <= n.Retries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(backoff):
+			}
+			backoff *= 2
+		}
+
+		if err = n.post(ctx, webhook, body); err == nil {
+			return nil
+		}
+		slog.Warn(fmt.Sprintf("alert warn: attempt %d to %s: %v", attempt+1, webhook, err))
+	}
+
+	return err
+}
+
+func (n *Notifier) post(ctx context.Context, webhook string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "rpinfo/"+version.Release())
+
+	resp, err := n.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status: %s", resp.Status)
+	}
+
+	return nil
+}
+
+func hostname() string {
+	name, err := os.Hostname()
+	if err != nil {
+		return "unknown"
+	}
+
+	return name
+}
@@ -0,0 +1,85 @@
+/*
+Copyright (c) 2025 Tobias Schäfer. All rights reserved.
+Licensed under the MIT license, see LICENSE in the project root for details.
+*/
+package alert
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type receiver struct {
+	mu       sync.Mutex
+	failures int
+	attempts int
+	payloads []map[string]any
+}
+
+func (rc *receiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	rc.attempts++
+	if rc.attempts <= rc.failures {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+
+	var payload map[string]any
+	_ = json.NewDecoder(r.Body).Decode(&payload)
+	rc.payloads = append(rc.payloads, payload)
+}
+
+func Test_NotifyRetriesWithBackoff(t *testing.T) {
+	rc := &receiver{failures: 2}
+	server := httptest.NewServer(rc)
+	defer server.Close()
+
+	n := NewNotifier([]string{server.URL})
+	n.Backoff = time.Millisecond
+
+	fired := epoch
+	n.Notify(context.Background(), Alert{Rule: "hot", Metric: "temp", State: StateFiring, Value: 81, FiredAt: &fired})
+
+	assert.Equal(t, 3, rc.attempts)
+	assert.Len(t, rc.payloads, 1)
+	assert.Equal(t, "firing", rc.payloads[0]["status"])
+	assert.Equal(t, "hot", rc.payloads[0]["rule"])
+	assert.Equal(t, 81.0, rc.payloads[0]["value"])
+	assert.NotEmpty(t, rc.payloads[0]["host"])
+}
+
+func Test_NotifyGivesUpAfterRetries(t *testing.T) {
+	rc := &receiver{failures: 10}
+	server := httptest.NewServer(rc)
+	defer server.Close()
+
+	n := NewNotifier([]string{server.URL})
+	n.Backoff = time.Millisecond
+	n.Retries = 2
+
+	n.Notify(context.Background(), Alert{Rule: "hot", State: StateInactive})
+
+	assert.Equal(t, 3, rc.attempts)
+	assert.Empty(t, rc.payloads)
+}
+
+func Test_NotifySendsResolvedStatus(t *testing.T) {
+	rc := &receiver{}
+	server := httptest.NewServer(rc)
+	defer server.Close()
+
+	n := NewNotifier([]string{server.URL, server.URL})
+	n.Notify(context.Background(), Alert{Rule: "hot", State: StateInactive})
+
+	assert.Len(t, rc.payloads, 2)
+	assert.Equal(t, "resolved", rc.payloads[0]["status"])
+}
@@ -0,0 +1,148 @@
+/*
+Copyright (c) 2025 Tobias Schäfer. All rights reserved.
+Licensed under the MIT license, see LICENSE in the project root for details.
+*/
+package board
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Root is the file system root the board information is read from.
+var Root = "/"
+
+// Info is the identity of the board. The fields decoded from the revision
+// code are empty for old-style codes.
+type Info struct {
+	Model        string `json:"model"`
+	Serial       string `json:"serial"`
+	Revision     string `json:"revision"`
+	Type         string `json:"type,omitempty"`
+	SoC          string `json:"soc,omitempty"`
+	Memory       uint64 `json:"memory,omitempty"`
+	Manufacturer string `json:"manufacturer,omitempty"`
+	PCBRevision  string `json:"pcb_revision,omitempty"`
+}
+
+var (
+	cacheMu sync.Mutex
+	cache   *cached
+)
+
+// cached is the board information read from a root
+type cached struct {
+	root string
+	info Info
+}
+
+// Read returns the board information from the device tree and
+// /proc/cpuinfo, fields are empty if unknown. The information doesn't
+// change at runtime and is read once per Root.
+func Read() Info {
+	cacheMu.Lock()
+	defer cacheMu.Unlock()
+
+	if cache == nil || cache.root != Root {
+		cache = &cached{root: Root, info: read()}
+	}
+
+	return cache.info
+}
+
+func read() Info {
+	cpu := cpuinfo()
+	info := Info{
+		Model:    deviceTree("model"),
+		Serial:   deviceTree("serial-number"),
+		Revision: cpu["Revision"],
+	}
+	if info.Model == "" {
+		info.Model = cpu["Model"]
+	}
+	if info.Serial == "" {
+		info.Serial = cpu["Serial"]
+	}
+
+	if revision, err := DecodeRevision(info.Revision); err == nil {
+		info.Type = revision.Type
+		info.SoC = revision.Processor
+		info.Memory = revision.Memory
+		info.Manufacturer = revision.Manufacturer
+		info.PCBRevision = revision.PCB
+	}
+
+	return info
+}
+
+// String returns a short description of the board, e.g. Raspberry Pi 4
+// Model B Rev 1.4 (BCM2711, 4GB), empty if unknown.
+func (i Info) String() string {
+	var details []string
+	if i.SoC != "" {
+		details = append(details, i.SoC)
+	}
+	if i.Memory > 0 {
+		details = append(details, FormatMemory(i.Memory))
+	}
+	if len(details) == 0 {
+		return i.Model
+	}
+
+	return fmt.Sprintf("%s (%s)", i.Model, strings.Join(details, ", "))
+}
+
+// Model returns the board model, e.g. Raspberry Pi 4 Model B Rev 1.4, empty
+// if unknown.
+func Model() string {
+	return Read().Model
+}
+
+// Serial returns the board serial number, empty if unknown.
+func Serial() string {
+	return Read().Serial
+}
+
+func deviceTree(name string) string {
+	raw, err := os.ReadFile(filepath.Join(Root, "proc", "device-tree", name))
+	if err != nil {
+		return ""
+	}
+
+	return strings.TrimRight(string(raw), "\x00\n")
+}
+
+// cpuinfo returns the fields of /proc/cpuinfo, the per processor fields of
+// the last processor.
+func cpuinfo() map[string]string {
+	fields := make(map[string]string)
+	raw, err := os.ReadFile(filepath.Join(Root, "proc", "cpuinfo"))
+	if err != nil {
+		return fields
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(raw))
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), ":")
+		if !ok {
+			continue
+		}
+		fields[strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+
+	return fields
+}
+
+// FormatMemory returns the memory size in bytes as MB or GB, e.g. 4GB.
+func FormatMemory(size uint64) string {
+	if size >= 1<<30 && size%(1<<30) == 0 {
+		return fmt.Sprintf("%dGB", size>>30)
+	}
+
+	return fmt.Sprintf("%dMB", size>>20)
+}
@@ -0,0 +1,162 @@
+/*
+Copyright (c) 2025 Tobias Schäfer. All rights reserved.
+Licensed under the MIT license, see LICENSE in the project root for details.
+*/
+package board
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func fixture(t *testing.T, files map[string]string) {
+	root := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("failed to create fixture: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("failed to create fixture: %v", err)
+		}
+	}
+
+	previous := Root
+	Root = root
+	t.Cleanup(func() { Root = previous })
+}
+
+func Test_ModelAndSerialReadDeviceTree(t *testing.T) {
+	fixture(t, map[string]string{
+		"proc/device-tree/model":         "Raspberry Pi 4 Model B Rev 1.4\x00",
+		"proc/device-tree/serial-number": "10000000abcdef01\x00",
+	})
+
+	assert.Equal(t, "Raspberry Pi 4 Model B Rev 1.4", Model())
+	assert.Equal(t, "10000000abcdef01", Serial())
+}
+
+func Test_ModelAndSerialAreEmptyIfUnknown(t *testing.T) {
+	fixture(t, nil)
+
+	assert.Empty(t, Model())
+	assert.Empty(t, Serial())
+}
+
+const cpuinfo4B = `processor	: 0
+BogoMIPS	: 108.00
+Features	: fp asimd evtstrm crc32 cpuid
+CPU implementer	: 0x41
+
+Hardware	: BCM2835
+Revision	: c03114
+Serial		: 10000000abcdef01
+Model		: Raspberry Pi 4 Model B Rev 1.4
+`
+
+func Test_ReadDecodesRevision(t *testing.T) {
+	fixture(t, map[string]string{
+		"proc/cpuinfo":           cpuinfo4B,
+		"proc/device-tree/model": "Raspberry Pi 4 Model B Rev 1.4\x00",
+	})
+
+	assert.Equal(t, Info{
+		Model:        "Raspberry Pi 4 Model B Rev 1.4",
+		Serial:       "10000000abcdef01",
+		Revision:     "c03114",
+		Type:         "4B",
+		SoC:          "BCM2711",
+		Memory:       4 << 30,
+		Manufacturer: "Sony UK",
+		PCBRevision:  "1.4",
+	}, Read())
+	assert.Equal(t, "Raspberry Pi 4 Model B Rev 1.4 (BCM2711, 4GB)", Read().String())
+}
+
+func Test_ReadKeepsOldStyleRevision(t *testing.T) {
+	fixture(t, map[string]string{
+		"proc/cpuinfo": "Revision\t: 000e\nModel\t\t: Raspberry Pi Model B Rev 2\n",
+	})
+
+	info := Read()
+	assert.Equal(t, "Raspberry Pi Model B Rev 2", info.Model)
+	assert.Equal(t, "000e", info.Revision)
+	assert.Empty(t, info.SoC)
+	assert.Equal(t, "Raspberry Pi Model B Rev 2", info.String())
+}
+
+func Test_DecodeRevision(t *testing.T) {
+	tests := []struct {
+		code     string
+		expected Revision
+	}{
+		{"a02082", Revision{Code: 0xa02082, Type: "3B", Processor: "BCM2837", Memory: 1 << 30, Manufacturer: "Sony UK", PCB: "1.2"}},
+		{"a22082", Revision{Code: 0xa22082, Type: "3B", Processor: "BCM2837", Memory: 1 << 30, Manufacturer: "Embest", PCB: "1.2"}},
+		{"902120", Revision{Code: 0x902120, Type: "Zero 2 W", Processor: "BCM2837", Memory: 512 << 20, Manufacturer: "Sony UK", PCB: "1.0"}},
+		{"d04170", Revision{Code: 0xd04170, Type: "5", Processor: "BCM2712", Memory: 8 << 30, Manufacturer: "Sony UK", PCB: "1.0"}},
+		{"e04171", Revision{Code: 0xe04171, Type: "5", Processor: "BCM2712", Memory: 16 << 30, Manufacturer: "Sony UK", PCB: "1.1"}},
+		{"c0ff70", Revision{Code: 0xc0ff70, Type: "0xf7", Processor: "0xf", Memory: 4 << 30, Manufacturer: "Sony UK", PCB: "1.0"}},
+	}
+
+	for _, tt := range tests {
+		revision, err := DecodeRevision(tt.code)
+		assert.NoError(t, err, tt.code)
+		assert.Equal(t, tt.expected, revision, tt.code)
+	}
+}
+
+func Test_DecodeRevisionRejectsOldStyleAndInvalid(t *testing.T) {
+	_, err := DecodeRevision("000e")
+	assert.EqualError(t, err, "old-style revision code: 000e")
+
+	_, err = DecodeRevision("")
+	assert.EqualError(t, err, "invalid revision code: ")
+}
+
+func Test_FormatMemory(t *testing.T) {
+	assert.Equal(t, "512MB", FormatMemory(512<<20))
+	assert.Equal(t, "2GB", FormatMemory(2<<30))
+}
+
+func Test_MeminfoReturnsBytes(t *testing.T) {
+	fixture(t, map[string]string{
+		"proc/meminfo": "MemTotal:        3884396 kB\nMemFree:         2718044 kB\nMemAvailable:    3318688 kB\nHugePages_Total:       0\n",
+	})
+
+	meminfo, err := Meminfo()
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]uint64{
+		"MemTotal":        3884396 << 10,
+		"MemFree":         2718044 << 10,
+		"MemAvailable":    3318688 << 10,
+		"HugePages_Total": 0,
+	}, meminfo)
+}
+
+func Test_MeminfoReturnsErrorIfUnreadable(t *testing.T) {
+	fixture(t, map[string]string{"proc/meminfo": "MemTotal: many kB\n"})
+	_, err := Meminfo()
+	assert.EqualError(t, err, `invalid meminfo: "MemTotal: many kB"`)
+
+	fixture(t, nil)
+	_, err = Meminfo()
+	assert.Error(t, err)
+}
+
+func Test_ReadIsCachedPerRoot(t *testing.T) {
+	fixture(t, map[string]string{
+		"proc/device-tree/model": "Raspberry Pi 4 Model B Rev 1.4\x00",
+	})
+	assert.Equal(t, "Raspberry Pi 4 Model B Rev 1.4", Read().Model)
+
+	os.WriteFile(filepath.Join(Root, "proc/device-tree/model"), []byte("Raspberry Pi 5 Model B Rev 1.0\x00"), 0o644)
+	assert.Equal(t, "Raspberry Pi 4 Model B Rev 1.4", Model())
+
+	fixture(t, map[string]string{
+		"proc/device-tree/model": "Raspberry Pi 5 Model B Rev 1.0\x00",
+	})
+	assert.Equal(t, "Raspberry Pi 5 Model B Rev 1.0", Model())
+}
@@ -0,0 +1,42 @@
+/*
+Copyright (c) 2025 Tobias Schäfer. All rights reserved.
+Licensed under the MIT license, see LICENSE in the project root for details.
+*/
+package board
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Meminfo returns the fields of /proc/meminfo, sizes in bytes and counts
+// as is, e.g. MemTotal.
+func Meminfo() (map[string]uint64, error) {
+	raw, err := os.ReadFile(filepath.Join(Root, "proc", "meminfo"))
+	if err != nil {
+		return nil, err
+	}
+
+	fields := make(map[string]uint64)
+	for line := range strings.SplitSeq(strings.TrimSpace(string(raw)), "\n") {
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid meminfo: %q", line)
+		}
+
+		number, unit := strings.TrimSpace(value), uint64(1)
+		if n, ok := strings.CutSuffix(number, " kB"); ok {
+			number, unit = n, 1<<10
+		}
+		size, err := strconv.ParseUint(number, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid meminfo: %q", line)
+		}
+		fields[key] = size * unit
+	}
+
+	return fields, nil
+}
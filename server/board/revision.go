@@ -0,0 +1,92 @@
+/*
+Copyright (c) 2025 Tobias Schäfer. All rights reserved.
+Licensed under the MIT license, see LICENSE in the project root for details.
+*/
+package board
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// Revision is a decoded new-style revision code of /proc/cpuinfo, see
+// https://www.raspberrypi.com/documentation/computers/raspberry-pi.html#raspberry-pi-revision-codes
+type Revision struct {
+	Code         uint32
+	Type         string
+	Processor    string
+	Memory       uint64
+	Manufacturer string
+	PCB          string
+}
+
+// Bit layout NOQuuuWuFMMMCCCCPPPPTTTTTTTTRRRR
+const newStyle = 1 << 23
+
+var types = map[uint32]string{
+	0x00: "A",
+	0x01: "B",
+	0x02: "A+",
+	0x03: "B+",
+	0x04: "2B",
+	0x05: "Alpha",
+	0x06: "CM1",
+	0x08: "3B",
+	0x09: "Zero",
+	0x0a: "CM3",
+	0x0c: "Zero W",
+	0x0d: "3B+",
+	0x0e: "3A+",
+	0x0f: "Internal use only",
+	0x10: "CM3+",
+	0x11: "4B",
+	0x12: "Zero 2 W",
+	0x13: "400",
+	0x14: "CM4",
+	0x15: "CM4S",
+	0x16: "Internal use only",
+	0x17: "5",
+	0x18: "CM5",
+	0x19: "500",
+	0x1a: "CM5 Lite",
+}
+
+var processors = []string{"BCM2835", "BCM2836", "BCM2837", "BCM2711", "BCM2712"}
+
+var manufacturers = []string{"Sony UK", "Egoman", "Embest", "Sony Japan", "Embest", "Stadium"}
+
+// DecodeRevision decodes a new-style revision code, e.g. c03114. Unknown
+// values of a field are returned as hexadecimal number.
+func DecodeRevision(code string) (Revision, error) {
+	value, err := strconv.ParseUint(code, 16, 32)
+	if err != nil {
+		return Revision{}, fmt.Errorf("invalid revision code: %s", code)
+	}
+	if value&newStyle == 0 {
+		return Revision{}, fmt.Errorf("old-style revision code: %s", code)
+	}
+
+	bits := func(shift, width uint) uint32 {
+		return uint32(value>>shift) & (1<<width - 1)
+	}
+	name := func(names []string, i uint32) string {
+		if int(i) < len(names) {
+			return names[i]
+		}
+		return fmt.Sprintf("0x%x", i)
+	}
+
+	kind, ok := types[bits(4, 8)]
+	if !ok {
+		kind = fmt.Sprintf("0x%02x", bits(4, 8))
+	}
+
+	return Revision{
+		Code:         uint32(value),
+		Type:         kind,
+		Processor:    name(processors, bits(12, 4)),
+		Memory:       256 << 20 << bits(20, 3),
+		Manufacturer: name(manufacturers, bits(16, 4)),
+		PCB:          fmt.Sprintf("1.%d", bits(0, 4)),
+	}, nil
+}
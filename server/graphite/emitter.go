@@ -0,0 +1,148 @@
+/*
+Copyright (c) 2025 Tobias Schäfer. All rights reserved.
+Licensed under the MIT license, see LICENSE in the project root for details.
+*/
+package graphite
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tschaefer/rpinfo/server/sampler"
+)
+
+// Maximum payload of a single UDP datagram, fits a common MTU
+const maxDatagram = 1432
+
+// Emitter sends every reading at the interval below the Prefix, e.g.
+// rpinfo.pi.volt.core, either as Graphite plaintext or as StatsD gauges over
+// TCP or UDP. A broken connection is dropped and dialed again on the next
+// interval.
+type Emitter struct {
+	Address  string
+	Network  string
+	Format   string
+	Prefix   string
+	Interval time.Duration
+	Snapshot func(ctx context.Context) (sampler.Snapshot, error)
+
+	conn net.Conn
+}
+
+// Prefix renders the prefix template, {host} is replaced by the hostname
+// with dots replaced by underscores.
+func Prefix(template string, host string) string {
+	return strings.ReplaceAll(template, "{host}", strings.ReplaceAll(host, ".", "_"))
+}
+
+// Run emits until the context is done.
+func (e *Emitter) Run(ctx context.Context) {
+	ticker := time.NewTicker(e.Interval)
+	defer ticker.Stop()
+	defer e.close()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if err := e.emit(ctx); err != nil {
+			slog.Warn(fmt.Sprintf("graphite warn: %v", err))
+		}
+	}
+}
+
+func (e *Emitter) emit(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, e.Interval)
+	defer cancel()
+
+	snapshot, err := e.Snapshot(ctx)
+	if err != nil {
+		return err
+	}
+
+	lines, err := e.lines(snapshot)
+	if err != nil {
+		return err
+	}
+
+	if e.conn == nil {
+		dialer := net.Dialer{Timeout: e.Interval}
+		if e.conn, err = dialer.DialContext(ctx, e.Network, e.Address); err != nil {
+			return err
+		}
+	}
+
+	deadline, _ := ctx.Deadline()
+	_ = e.conn.SetWriteDeadline(deadline)
+	for _, payload := range e.payloads(lines) {
+		if _, err := e.conn.Write(payload); err != nil {
+			e.close()
+			return err
+		}
+	}
+
+	return nil
+}
+
+// lines returns a line per reading in the format.
+func (e *Emitter) lines(snapshot sampler.Snapshot) ([][]byte, error) {
+	var lines [][]byte
+	for _, metric := range sampler.Metrics() {
+		value, ok := snapshot.Value(metric)
+		if !ok {
+			continue
+		}
+
+		path := e.Prefix + "." + metric
+		formatted := strconv.FormatFloat(value, 'f', -1, 64)
+		switch e.Format {
+		case "graphite":
+			lines = append(lines, fmt.Appendf(nil, "%s %s %d\n", path, formatted, snapshot.Time.Unix()))
+		case "statsd":
+			lines = append(lines, fmt.Appendf(nil, "%s:%s|g\n", path, formatted))
+		default:
+			return nil, fmt.Errorf("unknown format: %s", e.Format)
+		}
+	}
+
+	return lines, nil
+}
+
+// payloads joins the lines into a single write on a stream and into
+// datagrams without splitting a line on a packet connection.
+func (e *Emitter) payloads(lines [][]byte) [][]byte {
+	if !strings.HasPrefix(e.Network, "udp") {
+		return [][]byte{bytes.Join(lines, nil)}
+	}
+
+	var payloads [][]byte
+	var datagram []byte
+	for _, line := range lines {
+		if len(datagram) > 0 && len(datagram)+len(line) > maxDatagram {
+			payloads = append(payloads, datagram)
+			datagram = nil
+		}
+		datagram = append(datagram, line...)
+	}
+	if len(datagram) > 0 {
+		payloads = append(payloads, datagram)
+	}
+
+	return payloads
+}
+
+func (e *Emitter) close() {
+	if e.conn != nil {
+		_ = e.conn.Close()
+		e.conn = nil
+	}
+}
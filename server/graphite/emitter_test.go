@@ -0,0 +1,145 @@
+/*
+Copyright (c) 2025 Tobias Schäfer. All rights reserved.
+Licensed under the MIT license, see LICENSE in the project root for details.
+*/
+package graphite
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tschaefer/rpinfo/server/sampler"
+	"github.com/tschaefer/rpinfo/vcgencmd"
+)
+
+func newEmitter(network, address, format string) *Emitter {
+	return &Emitter{
+		Address:  address,
+		Network:  network,
+		Format:   format,
+		Prefix:   "rpinfo.pi",
+		Interval: time.Second,
+		Snapshot: func(ctx context.Context) (sampler.Snapshot, error) {
+			return sampler.Snapshot{
+				Time:        time.Unix(1700000000, 0),
+				Temperature: 42.8,
+				Voltages:    map[string]vcgencmd.Voltage{"core": 0.85},
+				Clocks:      map[string]vcgencmd.Frequency{"arm": 1500000000},
+				Throttled:   0x50005,
+			}, nil
+		},
+	}
+}
+
+// accept returns the lines of the first connection until it is closed.
+func accept(t *testing.T, listener net.Listener) <-chan []string {
+	received := make(chan []string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			received <- nil
+			return
+		}
+		defer conn.Close()
+
+		var lines []string
+		scanner := bufio.NewScanner(conn)
+		for scanner.Scan() {
+			lines = append(lines, scanner.Text())
+		}
+		received <- lines
+	}()
+
+	return received
+}
+
+func Test_Prefix(t *testing.T) {
+	assert.Equal(t, "rpinfo.pi_lab_local", Prefix("rpinfo.{host}", "pi.lab.local"))
+	assert.Equal(t, "lab.pi.rpinfo", Prefix("lab.{host}.rpinfo", "pi"))
+	assert.Equal(t, "rpinfo", Prefix("rpinfo", "pi"))
+}
+
+func Test_EmitterSendsGraphiteOverTCP(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer listener.Close()
+	received := accept(t, listener)
+
+	e := newEmitter("tcp", listener.Addr().String(), "graphite")
+	assert.NoError(t, e.emit(context.Background()))
+	e.close()
+
+	lines := <-received
+	assert.Contains(t, lines, "rpinfo.pi.temp 42.8 1700000000")
+	assert.Contains(t, lines, "rpinfo.pi.volt.core 0.85 1700000000")
+	assert.Contains(t, lines, "rpinfo.pi.clock.arm 1500000000 1700000000")
+	assert.Contains(t, lines, "rpinfo.pi.throttled 327685 1700000000")
+	assert.Contains(t, lines, "rpinfo.pi.throttled.under_voltage 1 1700000000")
+	assert.Contains(t, lines, "rpinfo.pi.throttled.frequency_capped 0 1700000000")
+}
+
+func Test_EmitterSendsStatsdOverUDP(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	e := newEmitter("udp", conn.LocalAddr().String(), "statsd")
+	assert.NoError(t, e.emit(context.Background()))
+	defer e.close()
+
+	buf := make([]byte, maxDatagram)
+	_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+	n, _, err := conn.ReadFrom(buf)
+	assert.NoError(t, err)
+
+	lines := strings.Split(strings.TrimSuffix(string(buf[:n]), "\n"), "\n")
+	assert.Equal(t, "rpinfo.pi.temp:42.8|g", lines[0])
+	assert.Contains(t, lines, "rpinfo.pi.volt.core:0.85|g")
+	assert.Contains(t, lines, "rpinfo.pi.throttled.under_voltage:1|g")
+}
+
+func Test_EmitterSplitsDatagrams(t *testing.T) {
+	e := newEmitter("udp", "", "statsd")
+	line := []byte(strings.Repeat("x", 500) + "\n")
+
+	payloads := e.payloads([][]byte{line, line, line, line})
+	assert.Len(t, payloads, 2)
+	for _, payload := range payloads {
+		assert.LessOrEqual(t, len(payload), maxDatagram)
+	}
+
+	e.Network = "tcp"
+	assert.Len(t, e.payloads([][]byte{line, line, line, line}), 1)
+}
+
+func Test_EmitterReconnects(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	address := listener.Addr().String()
+	listener.Close()
+
+	e := newEmitter("tcp", address, "graphite")
+	assert.Error(t, e.emit(context.Background()))
+	assert.Nil(t, e.conn)
+
+	listener, err = net.Listen("tcp", address)
+	assert.NoError(t, err)
+	defer listener.Close()
+	received := accept(t, listener)
+
+	assert.NoError(t, e.emit(context.Background()))
+	e.close()
+	assert.Contains(t, <-received, "rpinfo.pi.temp 42.8 1700000000")
+}
+
+func Test_EmitterRejectsUnknownFormat(t *testing.T) {
+	e := newEmitter("tcp", "", "carbon")
+
+	assert.EqualError(t, e.emit(context.Background()), "unknown format: carbon")
+}
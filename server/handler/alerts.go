@@ -0,0 +1,17 @@
+/*
+Copyright (c) 2025 Tobias Schäfer. All rights reserved.
+Licensed under the MIT license, see LICENSE in the project root for details.
+*/
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/tschaefer/rpinfo/server/log"
+)
+
+func (h Handle) Alerts(w http.ResponseWriter, r *http.Request) {
+	go log.RequestInfo(r, http.StatusOK, "Fetched alerts")
+	json.NewEncoder(w).Encode(map[string]any{"alerts": h.Alerting.Alerts()})
+}
@@ -0,0 +1,18 @@
+/*
+Copyright (c) 2025 Tobias Schäfer. All rights reserved.
+Licensed under the MIT license, see LICENSE in the project root for details.
+*/
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/tschaefer/rpinfo/server/board"
+	"github.com/tschaefer/rpinfo/server/log"
+)
+
+func (h Handle) Board(w http.ResponseWriter, r *http.Request) {
+	go log.RequestInfo(r, http.StatusOK, "Fetched board")
+	json.NewEncoder(w).Encode(board.Read())
+}
@@ -0,0 +1,43 @@
+/*
+Copyright (c) 2025 Tobias Schäfer. All rights reserved.
+Licensed under the MIT license, see LICENSE in the project root for details.
+*/
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/tschaefer/rpinfo/server/log"
+	"github.com/tschaefer/rpinfo/vcgencmd"
+)
+
+// Codecs the firmware doesn't know are listed as unsupported instead of
+// failing the request.
+type codecs struct {
+	Codecs      map[string]bool `json:"codecs"`
+	Unsupported []string        `json:"unsupported"`
+}
+
+func (h Handle) Codecs(w http.ResponseWriter, r *http.Request) {
+	e := &execer{h: h}
+	response := codecs{Codecs: make(map[string]bool), Unsupported: []string{}}
+
+	for _, codec := range vcgencmd.Codecs {
+		enabled, err := vcgencmd.CodecEnabled(r.Context(), e, codec)
+		switch {
+		case err == nil:
+			response.Codecs[codec] = enabled
+		case errors.Is(err, vcgencmd.ErrCodecUnsupported):
+			response.Unsupported = append(response.Unsupported, codec)
+		default:
+			serverError(w, r, err)
+			return
+		}
+	}
+
+	h.cacheAge(w, e.age)
+	go log.RequestInfo(r, http.StatusOK, "Fetched codecs")
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,25 @@
+/*
+Copyright (c) 2025 Tobias Schäfer. All rights reserved.
+Licensed under the MIT license, see LICENSE in the project root for details.
+*/
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/tschaefer/rpinfo/server/log"
+)
+
+func (h Handle) Events(w http.ResponseWriter, r *http.Request) {
+	since, err := parseTime(r.URL.Query().Get("since"), time.Now(), time.Time{})
+	if err != nil {
+		badRequest(w, r, fmt.Sprintf("invalid since: %v", err))
+		return
+	}
+
+	go log.RequestInfo(r, http.StatusOK, "Fetched throttled events")
+	json.NewEncoder(w).Encode(map[string]any{"events": h.Watcher.Events.Since(since)})
+}
@@ -0,0 +1,52 @@
+/*
+Copyright (c) 2025 Tobias Schäfer. All rights reserved.
+Licensed under the MIT license, see LICENSE in the project root for details.
+*/
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/tschaefer/rpinfo/server/log"
+	"github.com/tschaefer/rpinfo/vcgencmd"
+)
+
+// The bootloader is omitted on boards without EEPROM, e.g. a Raspberry Pi 3.
+type firmware struct {
+	Firmware         vcgencmd.Build               `json:"firmware"`
+	Bootloader       *vcgencmd.Bootloader         `json:"bootloader,omitempty"`
+	BootloaderConfig map[string]map[string]string `json:"bootloader_config,omitempty"`
+}
+
+func (h Handle) Firmware(w http.ResponseWriter, r *http.Request) {
+	e := &execer{h: h}
+
+	var response firmware
+	var err error
+	response.Firmware, err = vcgencmd.Version(r.Context(), e)
+	if err != nil {
+		serverError(w, r, err)
+		return
+	}
+
+	bootloader, err := vcgencmd.BootloaderVersion(r.Context(), e)
+	switch {
+	case errors.Is(err, vcgencmd.ErrCommandUnsupported):
+	case err != nil:
+		serverError(w, r, err)
+		return
+	default:
+		response.Bootloader = &bootloader
+		response.BootloaderConfig, err = vcgencmd.BootloaderConfig(r.Context(), e)
+		if err != nil {
+			serverError(w, r, err)
+			return
+		}
+	}
+
+	h.cacheAge(w, e.age)
+	go log.RequestInfo(r, http.StatusOK, "Fetched firmware")
+	json.NewEncoder(w).Encode(response)
+}
@@ -5,42 +5,157 @@ Licensed under the MIT license, see LICENSE in the project root for details.
 package handler
 
 import (
+	"context"
 	"encoding/json"
-	"iter"
+	"errors"
 	"maps"
 	"net/http"
-	"strings"
+	"strconv"
+	"time"
 
+	"github.com/tschaefer/rpinfo/server/alert"
+	"github.com/tschaefer/rpinfo/server/influx"
 	"github.com/tschaefer/rpinfo/server/log"
+	"github.com/tschaefer/rpinfo/server/sampler"
+	"github.com/tschaefer/rpinfo/server/watcher"
 	"github.com/tschaefer/rpinfo/vcgencmd"
 )
 
 type Handle struct {
-	Cmd vcgencmd.Exec
+	Cmd     vcgencmd.Exec
+	Timeout time.Duration
+	Sampler *sampler.Sampler
+	// Limits concurrent websocket subscribers
+	Subscribers *Limiter
+	Watcher     *watcher.Watcher
+	Alerting    *alert.Engine
+	// Exposes the legacy metric names in addition
+	MetricsLegacy bool
+	InfluxFormat  influx.Format
+	// Hash of the running firmware of the info metric, read once at startup
+	FirmwareHash string
+}
+
+// The age is the time since a cached result was sampled, zero if the
+// command isn't cached.
+func (h Handle) run(ctx context.Context, args ...string) (map[string]string, time.Duration, error) {
+	if h.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, h.Timeout)
+		defer cancel()
+	}
+
+	if cached, ok := h.Cmd.(vcgencmd.Cached); ok {
+		return cached.RunAge(ctx, args...)
+	}
+
+	out, err := h.Cmd.RunContext(ctx, args...)
+	return out, 0, err
+}
+
+// runRaw returns the output of a command as is and its age like run, the
+// Cmd must implement vcgencmd.Raw.
+func (h Handle) runRaw(ctx context.Context, args ...string) (string, time.Duration, error) {
+	if h.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, h.Timeout)
+		defer cancel()
+	}
+
+	if cached, ok := h.Cmd.(vcgencmd.Cached); ok {
+		return cached.RunRawAge(ctx, args...)
+	}
+
+	out, err := vcgencmd.RunRaw(ctx, h.Cmd, args...)
+	return out, 0, err
+}
+
+// execer runs the commands of a handle with its timeout and cache and keeps
+// the age of the oldest cached result.
+type execer struct {
+	h   Handle
+	age time.Duration
+}
+
+func (e *execer) Run(args ...string) (map[string]string, error) {
+	return e.RunContext(context.Background(), args...)
+}
+
+func (e *execer) RunContext(ctx context.Context, args ...string) (map[string]string, error) {
+	out, age, err := e.h.run(ctx, args...)
+	e.age = max(e.age, age)
+	return out, err
+}
+
+func (e *execer) RunRaw(ctx context.Context, args ...string) (string, error) {
+	out, age, err := e.h.runRaw(ctx, args...)
+	e.age = max(e.age, age)
+	return out, err
 }
 
 func runCmd(h Handle, w http.ResponseWriter, r *http.Request, args ...string) map[string]string {
-	out, err := h.Cmd.Run(args...)
+	out, age, err := h.run(r.Context(), args...)
 	if err != nil {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusInternalServerError)
-
-		go log.RequestError(r, http.StatusInternalServerError, err.Error())
-		json.NewEncoder(w).Encode(map[string]string{"detail": "internal server error"})
+		serverError(w, r, err)
 		return nil
 	}
 
+	h.cacheAge(w, age)
 	return out
 }
 
+// Report the age of the oldest cached result in seconds.
+func (h Handle) cacheAge(w http.ResponseWriter, age time.Duration) {
+	if _, ok := h.Cmd.(vcgencmd.Cached); !ok {
+		return
+	}
+
+	header := w.Header()
+	if prev, err := strconv.ParseFloat(header.Get("X-Rpinfo-Cache-Age"), 64); err == nil && prev >= age.Seconds() {
+		return
+	}
+	header.Set("X-Rpinfo-Cache-Age", strconv.FormatFloat(age.Seconds(), 'f', 3, 64))
+}
+
+func serverError(w http.ResponseWriter, r *http.Request, err error) {
+	status, detail := http.StatusInternalServerError, "internal server error"
+	if errors.Is(err, context.DeadlineExceeded) {
+		status, detail = http.StatusGatewayTimeout, "gateway timeout"
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	go log.RequestError(r, status, err.Error())
+	json.NewEncoder(w).Encode(map[string]string{"detail": detail})
+}
+
+// The raw vcgencmd string values are served on request, the default are
+// typed values with units.
+func legacy(r *http.Request) bool {
+	return r.URL.Query().Get("legacy") == "true"
+}
+
 func (h Handle) Temperature(w http.ResponseWriter, r *http.Request) {
 	temp := runCmd(h, w, r, "measure_temp")
 	if temp == nil {
 		return
 	}
 
+	if legacy(r) {
+		go log.RequestInfo(r, http.StatusOK, "Fetched temperature")
+		json.NewEncoder(w).Encode(temp)
+		return
+	}
+
+	value, err := vcgencmd.ParseTemperature(temp["temp"])
+	if err != nil {
+		serverError(w, r, err)
+		return
+	}
+
 	go log.RequestInfo(r, http.StatusOK, "Fetched temperature")
-	json.NewEncoder(w).Encode(temp)
+	json.NewEncoder(w).Encode(map[string]vcgencmd.Temperature{"temp": value})
 }
 
 func (h Handle) Configuration(w http.ResponseWriter, r *http.Request) {
@@ -60,15 +175,30 @@ func (h Handle) Configuration(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h Handle) Voltages(w http.ResponseWriter, r *http.Request) {
-	options := []string{"core", "sdram_c", "sdram_i", "sdram_p"}
-	voltages := make(map[string]string)
-	for _, opt := range options {
+	raw := make(map[string]string)
+	for _, opt := range vcgencmd.Rails {
 		out := runCmd(h, w, r, "measure_volts", opt)
 		if out == nil {
 			return
 		}
 
-		voltages[opt] = out["volt"]
+		raw[opt] = out["volt"]
+	}
+
+	if legacy(r) {
+		go log.RequestInfo(r, http.StatusOK, "Fetched voltages")
+		json.NewEncoder(w).Encode(raw)
+		return
+	}
+
+	voltages := make(map[string]vcgencmd.Voltage)
+	for opt, v := range raw {
+		value, err := vcgencmd.ParseVoltage(v)
+		if err != nil {
+			serverError(w, r, err)
+			return
+		}
+		voltages[opt] = value
 	}
 
 	go log.RequestInfo(r, http.StatusOK, "Fetched voltages")
@@ -81,13 +211,26 @@ func (h Handle) Throttled(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if r.URL.Query().Get("human") == "true" {
-		messages, _ := parseThrottledHex(throttled["throttled"])
-		message := strings.Join(messages, ", ")
-		if len(message) == 0 {
-			message = "No throttling"
-		}
-		throttled["throttled"] = message
+	value, err := vcgencmd.ParseThrottled(throttled["throttled"])
+	if err != nil {
+		serverError(w, r, err)
+		return
+	}
+
+	query := r.URL.Query()
+	switch {
+	case query.Get("detail") == "true":
+		go log.RequestInfo(r, http.StatusOK, "Fetched throttled status")
+		json.NewEncoder(w).Encode(map[string]throttledDetail{
+			"throttled": newThrottledDetail(throttled["throttled"], value),
+		})
+		return
+	case query.Get("human") == "true":
+		throttled["throttled"] = humanThrottled(value)
+	case !legacy(r):
+		go log.RequestInfo(r, http.StatusOK, "Fetched throttled status")
+		json.NewEncoder(w).Encode(map[string]vcgencmd.Throttled{"throttled": value})
+		return
 	}
 
 	go log.RequestInfo(r, http.StatusOK, "Fetched throttled status")
@@ -95,27 +238,30 @@ func (h Handle) Throttled(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h Handle) Clock(w http.ResponseWriter, r *http.Request) {
-	options := []string{
-		"arm", "core", "h264", "isp",
-		"v3d", "uart", "pwm", "emmc",
-		"pixel", "vec", "hdmi", "dpi",
-	}
-	clock := make(map[string]string)
-	for _, opt := range options {
+	raw := make(map[string]string)
+	for _, opt := range vcgencmd.Clocks {
 		out := runCmd(h, w, r, "measure_clock", opt)
 		if out == nil {
 			return
 		}
 
-		value := func() string {
-			next, stop := iter.Pull(maps.Values(out))
-			defer stop()
+		raw[opt] = vcgencmd.FirstValue(out)
+	}
 
-			v, _ := next()
-			return v
-		}
+	if legacy(r) {
+		go log.RequestInfo(r, http.StatusOK, "Fetched clock rates")
+		json.NewEncoder(w).Encode(raw)
+		return
+	}
 
-		clock[opt] = value()
+	clock := make(map[string]vcgencmd.Frequency)
+	for opt, v := range raw {
+		value, err := vcgencmd.ParseFrequency(v)
+		if err != nil {
+			serverError(w, r, err)
+			return
+		}
+		clock[opt] = value
 	}
 
 	go log.RequestInfo(r, http.StatusOK, "Fetched clock rates")
@@ -5,13 +5,30 @@ Licensed under the MIT license, see LICENSE in the project root for details.
 package handler
 
 import (
+	"bufio"
+	"context"
+	"encoding/json"
 	"fmt"
+	"maps"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/gorilla/mux"
+	"github.com/gorilla/websocket"
+	"github.com/tschaefer/rpinfo/server/alert"
 	"github.com/tschaefer/rpinfo/server/assets"
+	"github.com/tschaefer/rpinfo/server/board"
+	"github.com/tschaefer/rpinfo/server/influx"
+	"github.com/tschaefer/rpinfo/server/middleware"
+	"github.com/tschaefer/rpinfo/server/sampler"
+	"github.com/tschaefer/rpinfo/server/watcher"
+	"github.com/tschaefer/rpinfo/vcgencmd"
 )
 
 type mockRunnerSuccess struct{}
@@ -46,17 +63,140 @@ func (m mockRunnerSuccess) Run(args ...string) (map[string]string, error) {
 		default:
 			return map[string]string{"freq": "0"}, nil
 		}
+	case "codec_enabled":
+		switch args[1] {
+		case "H264", "MJPG", "MPG4":
+			return map[string]string{args[1]: "enabled"}, nil
+		case "MPG2", "WVC1":
+			return map[string]string{args[1]: "disabled"}, nil
+		case "HEVC":
+			return nil, fmt.Errorf("vcgencmd error: %w", vcgencmd.ErrInvalidArguments)
+		default:
+			return map[string]string{"error": "2", "error_msg": "Invalid arguments"}, nil
+		}
+	case "get_mem":
+		switch args[1] {
+		case "arm":
+			return map[string]string{"arm": "948M"}, nil
+		case "gpu":
+			return map[string]string{"gpu": "76M"}, nil
+		case "malloc_total":
+			return map[string]string{"malloc_total": "14M"}, nil
+		default:
+			return nil, fmt.Errorf("vcgencmd error: %w", vcgencmd.ErrInvalidArguments)
+		}
 	default:
 		return nil, nil
 	}
 }
 
+func (m mockRunnerSuccess) RunContext(ctx context.Context, args ...string) (map[string]string, error) {
+	return m.Run(args...)
+}
+
+// RunRaw returns the firmware version or else the output as key=value
+// lines like the firmware.
+func (m mockRunnerSuccess) RunRaw(ctx context.Context, args ...string) (string, error) {
+	if args[0] == "version" {
+		return firmwareVersion, nil
+	}
+
+	out, err := m.RunContext(ctx, args...)
+	if err != nil {
+		return "", err
+	}
+
+	var lines []string
+	for _, key := range slices.Sorted(maps.Keys(out)) {
+		lines = append(lines, key+"="+out[key])
+	}
+	return strings.Join(lines, "\n") + "\n", nil
+}
+
 type mockRunnerError struct{}
 
 func (m mockRunnerError) Run(args ...string) (map[string]string, error) {
 	return nil, fmt.Errorf("command failed")
 }
 
+func (m mockRunnerError) RunContext(ctx context.Context, args ...string) (map[string]string, error) {
+	return m.Run(args...)
+}
+
+type mockRunnerHanging struct{}
+
+func (m mockRunnerHanging) Run(args ...string) (map[string]string, error) {
+	return m.RunContext(context.Background(), args...)
+}
+
+func (m mockRunnerHanging) RunContext(ctx context.Context, args ...string) (map[string]string, error) {
+	<-ctx.Done()
+	return nil, fmt.Errorf("vcgencmd error: %w", ctx.Err())
+}
+
+func (m mockRunnerHanging) RunRaw(ctx context.Context, args ...string) (string, error) {
+	<-ctx.Done()
+	return "", fmt.Errorf("vcgencmd error: %w", ctx.Err())
+}
+
+type mockRunnerOutput map[string]string
+
+func (m mockRunnerOutput) Run(args ...string) (map[string]string, error) {
+	return m, nil
+}
+
+func (m mockRunnerOutput) RunContext(ctx context.Context, args ...string) (map[string]string, error) {
+	return m.Run(args...)
+}
+
+const (
+	firmwareVersion   = "Oct 17 2023 15:39:16 \nCopyright (c) 2012 Broadcom\nversion 30aa0d70ab280427ba04ebc718c81d4350b9d394 (clean) (release) (start)\n"
+	bootloaderVersion = "2023/01/11 17:40:52\nversion 8ba17717fbcedd4c3b6d4bce7e50c7af4155cba9 (release)\ntimestamp 1673458852\nupdate-time 1676887458\ncapabilities 0x0000007f\n"
+	bootloaderConfig  = "[all]\nBOOT_UART=0\nBOOT_ORDER=0xf41\n"
+)
+
+const meminfo = "MemTotal:        3884396 kB\nMemFree:         2718044 kB\nMemAvailable:    3318688 kB\n"
+
+// mockRunnerRaw returns the raw output by command, commands without output
+// fail like unsupported ones.
+type mockRunnerRaw map[string]string
+
+func (m mockRunnerRaw) Run(args ...string) (map[string]string, error) {
+	return nil, fmt.Errorf("command failed")
+}
+
+func (m mockRunnerRaw) RunContext(ctx context.Context, args ...string) (map[string]string, error) {
+	return m.Run(args...)
+}
+
+func (m mockRunnerRaw) RunRaw(ctx context.Context, args ...string) (string, error) {
+	out, ok := m[args[0]]
+	if !ok {
+		return "", fmt.Errorf("vcgencmd error: %w", vcgencmd.ErrCommandUnsupported)
+	}
+	return out, nil
+}
+
+// mockRunnerFailingRaw returns the raw output by command, commands without
+// output fail with an I/O error.
+type mockRunnerFailingRaw map[string]string
+
+func (m mockRunnerFailingRaw) Run(args ...string) (map[string]string, error) {
+	return nil, fmt.Errorf("command failed")
+}
+
+func (m mockRunnerFailingRaw) RunContext(ctx context.Context, args ...string) (map[string]string, error) {
+	return m.Run(args...)
+}
+
+func (m mockRunnerFailingRaw) RunRaw(ctx context.Context, args ...string) (string, error) {
+	out, ok := m[args[0]]
+	if !ok {
+		return "", fmt.Errorf("vcgencmd error: mailbox response code 0x80000001")
+	}
+	return out, nil
+}
+
 func Test_TemperatureReturnsJSON(t *testing.T) {
 	req := httptest.NewRequest("GET", "/temperature", nil)
 	rr := httptest.NewRecorder()
@@ -65,6 +205,27 @@ func Test_TemperatureReturnsJSON(t *testing.T) {
 	handler := http.HandlerFunc(Handler.Temperature)
 	handler.ServeHTTP(rr, req)
 
+	if status := rr.Code; status != http.StatusOK {
+		t.Errorf("handler returned wrong status code: got %v want %v",
+			status, http.StatusOK)
+	}
+	expected := `{"temp":{"value":45,"unit":"celsius"}}`
+	got := rr.Body.String()
+	got = strings.TrimSpace(got)
+	if got != expected {
+		t.Errorf("handler returned unexpected body: got %v want %v",
+			rr.Body.String(), expected)
+	}
+}
+
+func Test_TemperatureReturnsLegacyJSON(t *testing.T) {
+	req := httptest.NewRequest("GET", "/temperature?legacy=true", nil)
+	rr := httptest.NewRecorder()
+
+	Handler := Handle{Cmd: mockRunnerSuccess{}}
+	handler := http.HandlerFunc(Handler.Temperature)
+	handler.ServeHTTP(rr, req)
+
 	if status := rr.Code; status != http.StatusOK {
 		t.Errorf("handler returned wrong status code: got %v want %v",
 			status, http.StatusOK)
@@ -92,6 +253,56 @@ func Test_TemperatureReturnsServerErrorIfCommandFails(t *testing.T) {
 	}
 }
 
+func Test_TemperatureReturnsGatewayTimeoutIfCommandTimesOut(t *testing.T) {
+	req := httptest.NewRequest("GET", "/temperature", nil)
+	rr := httptest.NewRecorder()
+
+	Handler := Handle{Cmd: mockRunnerHanging{}, Timeout: 10 * time.Millisecond}
+	handler := http.HandlerFunc(Handler.Temperature)
+	handler.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusGatewayTimeout {
+		t.Errorf("handler returned wrong status code: got %v want %v",
+			status, http.StatusGatewayTimeout)
+	}
+	expected := `{"detail":"gateway timeout"}`
+	got := rr.Body.String()
+	got = strings.TrimSpace(got)
+	if got != expected {
+		t.Errorf("handler returned unexpected body: got %v want %v",
+			rr.Body.String(), expected)
+	}
+}
+
+func Test_TemperatureReturnsCacheAgeIfCached(t *testing.T) {
+	req := httptest.NewRequest("GET", "/temperature", nil)
+	rr := httptest.NewRecorder()
+
+	Handler := Handle{Cmd: vcgencmd.NewCache(mockRunnerSuccess{}, time.Minute, nil)}
+	handler := http.HandlerFunc(Handler.Temperature)
+	handler.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Errorf("handler returned wrong status code: got %v want %v",
+			status, http.StatusOK)
+	}
+	if age := rr.Header().Get("X-Rpinfo-Cache-Age"); age != "0.000" {
+		t.Errorf("handler returned wrong cache age: got %v want %v",
+			age, "0.000")
+	}
+
+	req = httptest.NewRequest("GET", "/temperature", nil)
+	rr = httptest.NewRecorder()
+
+	Handler = Handle{Cmd: mockRunnerSuccess{}}
+	handler = http.HandlerFunc(Handler.Temperature)
+	handler.ServeHTTP(rr, req)
+
+	if age := rr.Header().Get("X-Rpinfo-Cache-Age"); age != "" {
+		t.Errorf("handler returned unexpected cache age: got %v", age)
+	}
+}
+
 func Test_VoltagesReturnsJSON(t *testing.T) {
 	req := httptest.NewRequest("GET", "/voltages", nil)
 	rr := httptest.NewRecorder()
@@ -100,6 +311,28 @@ func Test_VoltagesReturnsJSON(t *testing.T) {
 	handler := http.HandlerFunc(Handler.Voltages)
 	handler.ServeHTTP(rr, req)
 
+	if status := rr.Code; status != http.StatusOK {
+		t.Errorf("handler returned wrong status code: got %v want %v",
+			status, http.StatusOK)
+	}
+	expected := `{"core":{"value":1.35,"unit":"volt"},"sdram_c":{"value":1.2,"unit":"volt"},` +
+		`"sdram_i":{"value":1.2,"unit":"volt"},"sdram_p":{"value":1.225,"unit":"volt"}}`
+	got := rr.Body.String()
+	got = strings.TrimSpace(got)
+	if got != expected {
+		t.Errorf("handler returned unexpected body: got %v want %v",
+			rr.Body.String(), expected)
+	}
+}
+
+func Test_VoltagesReturnsLegacyJSON(t *testing.T) {
+	req := httptest.NewRequest("GET", "/voltages?legacy=true", nil)
+	rr := httptest.NewRecorder()
+
+	Handler := Handle{Cmd: mockRunnerSuccess{}}
+	handler := http.HandlerFunc(Handler.Voltages)
+	handler.ServeHTTP(rr, req)
+
 	if status := rr.Code; status != http.StatusOK {
 		t.Errorf("handler returned wrong status code: got %v want %v",
 			status, http.StatusOK)
@@ -170,6 +403,27 @@ func Test_ThrottledReturnsJSON(t *testing.T) {
 	handler := http.HandlerFunc(Handler.Throttled)
 	handler.ServeHTTP(rr, req)
 
+	if status := rr.Code; status != http.StatusOK {
+		t.Errorf("handler returned wrong status code: got %v want %v",
+			status, http.StatusOK)
+	}
+	expected := `{"throttled":{"value":327680,"hex":"0x50000"}}`
+	got := rr.Body.String()
+	got = strings.TrimSpace(got)
+	if got != expected {
+		t.Errorf("handler returned unexpected body: got %v want %v",
+			rr.Body.String(), expected)
+	}
+}
+
+func Test_ThrottledReturnsLegacyJSON(t *testing.T) {
+	req := httptest.NewRequest("GET", "/throttled?legacy=true", nil)
+	rr := httptest.NewRecorder()
+
+	Handler := Handle{Cmd: mockRunnerSuccess{}}
+	handler := http.HandlerFunc(Handler.Throttled)
+	handler.ServeHTTP(rr, req)
+
 	if status := rr.Code; status != http.StatusOK {
 		t.Errorf("handler returned wrong status code: got %v want %v",
 			status, http.StatusOK)
@@ -182,7 +436,7 @@ func Test_ThrottledReturnsJSON(t *testing.T) {
 			rr.Body.String(), expected)
 	}
 
-	req = httptest.NewRequest("GET", "/throttled?human=true", nil)
+	req = httptest.NewRequest("GET", "/throttled?legacy=true&human=true", nil)
 	rr = httptest.NewRecorder()
 
 	Handler = Handle{Cmd: mockRunnerSuccess{}}
@@ -202,6 +456,47 @@ func Test_ThrottledReturnsJSON(t *testing.T) {
 	}
 }
 
+func Test_ThrottledReturnsDetailJSON(t *testing.T) {
+	req := httptest.NewRequest("GET", "/throttled?detail=true", nil)
+	rr := httptest.NewRecorder()
+
+	Handler := Handle{Cmd: mockRunnerOutput{"throttled": "0x80050001"}}
+	handler := http.HandlerFunc(Handler.Throttled)
+	handler.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Errorf("handler returned wrong status code: got %v want %v",
+			status, http.StatusOK)
+	}
+	expected := `{"throttled":{"hex":"0x80050001","value":2147811329,"flags":{` +
+		`"frequency_capped":false,"frequency_capped_occurred":false,` +
+		`"soft_temp_limit":false,"soft_temp_limit_occurred":false,` +
+		`"throttling":false,"throttling_occurred":true,` +
+		`"under_voltage":true,"under_voltage_occurred":true},"unknown":[31]}}`
+	got := rr.Body.String()
+	got = strings.TrimSpace(got)
+	if got != expected {
+		t.Errorf("handler returned unexpected body: got %v want %v",
+			rr.Body.String(), expected)
+	}
+}
+
+func Test_ThrottledReturnsServerErrorIfHexIsInvalid(t *testing.T) {
+	for _, query := range []string{"", "?legacy=true&human=true", "?detail=true"} {
+		req := httptest.NewRequest("GET", "/throttled"+query, nil)
+		rr := httptest.NewRecorder()
+
+		Handler := Handle{Cmd: mockRunnerOutput{"throttled": "0xZZ"}}
+		handler := http.HandlerFunc(Handler.Throttled)
+		handler.ServeHTTP(rr, req)
+
+		if status := rr.Code; status != http.StatusInternalServerError {
+			t.Errorf("handler returned wrong status code for %q: got %v want %v",
+				query, status, http.StatusInternalServerError)
+		}
+	}
+}
+
 func Test_ThrottledReturnsServerErrorIfCommandFails(t *testing.T) {
 	req := httptest.NewRequest("GET", "/throttled", nil)
 	rr := httptest.NewRecorder()
@@ -247,6 +542,31 @@ func Test_MeasureClockReturnsJSON(t *testing.T) {
 	handler := http.HandlerFunc(Handler.Clock)
 	handler.ServeHTTP(rr, req)
 
+	if status := rr.Code; status != http.StatusOK {
+		t.Errorf("handler returned wrong status code: got %v want %v",
+			status, http.StatusOK)
+	}
+	expected := `{"arm":{"value":600000000,"unit":"hertz"},"core":{"value":250000000,"unit":"hertz"},` +
+		`"dpi":{"value":0,"unit":"hertz"},"emmc":{"value":0,"unit":"hertz"},"h264":{"value":0,"unit":"hertz"},` +
+		`"hdmi":{"value":0,"unit":"hertz"},"isp":{"value":0,"unit":"hertz"},"pixel":{"value":0,"unit":"hertz"},` +
+		`"pwm":{"value":0,"unit":"hertz"},"uart":{"value":0,"unit":"hertz"},"v3d":{"value":0,"unit":"hertz"},` +
+		`"vec":{"value":0,"unit":"hertz"}}`
+	got := rr.Body.String()
+	got = strings.TrimSpace(got)
+	if got != expected {
+		t.Errorf("handler returned unexpected body: got %v want %v",
+			rr.Body.String(), expected)
+	}
+}
+
+func Test_MeasureClockReturnsLegacyJSON(t *testing.T) {
+	req := httptest.NewRequest("GET", "/measure_clock?legacy=true", nil)
+	rr := httptest.NewRecorder()
+
+	Handler := Handle{Cmd: mockRunnerSuccess{}}
+	handler := http.HandlerFunc(Handler.Clock)
+	handler.ServeHTTP(rr, req)
+
 	if status := rr.Code; status != http.StatusOK {
 		t.Errorf("handler returned wrong status code: got %v want %v",
 			status, http.StatusOK)
@@ -275,10 +595,18 @@ func Test_MeasureClockReturnsServerErrorIfCommandFails(t *testing.T) {
 }
 
 func Test_MetricsReturnsPrometheusText(t *testing.T) {
+	board.Root = t.TempDir()
+	os.MkdirAll(filepath.Join(board.Root, "proc/device-tree"), 0o755)
+	os.WriteFile(filepath.Join(board.Root, "proc/device-tree/model"), []byte("Raspberry Pi 4 Model B Rev 1.4\x00"), 0o644)
+	os.WriteFile(filepath.Join(board.Root, "proc/cpuinfo"), []byte("Revision\t: c03114\n"), 0o644)
+	os.WriteFile(filepath.Join(board.Root, "proc/meminfo"), []byte(meminfo), 0o644)
+	defer func() { board.Root = "/" }()
+
 	req := httptest.NewRequest("GET", "/metrics", nil)
 	rr := httptest.NewRecorder()
 
-	handler := http.HandlerFunc(Metrics)
+	Handler := Handle{Cmd: mockRunnerSuccess{}, FirmwareHash: "30aa0d70ab280427ba04ebc718c81d4350b9d394"}
+	handler := http.HandlerFunc(Handler.Metrics)
 	handler.ServeHTTP(rr, req)
 
 	if status := rr.Code; status != http.StatusOK {
@@ -286,17 +614,876 @@ func Test_MetricsReturnsPrometheusText(t *testing.T) {
 			status, http.StatusOK)
 	}
 
-	expected := "rpi_clock_arm 0\nrpi_clock_core 0\nrpi_clock_dpi 0\n" +
-		"rpi_clock_emmc 0\nrpi_clock_h264 0\nrpi_clock_hdmi 0\n" +
-		"rpi_clock_isp 0\nrpi_clock_pixel 0\nrpi_clock_pwm 0\n" +
-		"rpi_clock_uart 0\nrpi_clock_v3d 0\nrpi_clock_vec 0\n" +
-		"rpi_temperature 0\nrpi_voltage_core 0\nrpi_voltage_sdram_c 0\n" +
-		"rpi_voltage_sdram_i 0\nrpi_voltage_sdram_p 0"
+	expected := "# HELP rpi_info Information about rpinfo and the board.\n" +
+		"# TYPE rpi_info gauge\n" +
+		"rpi_info{version=\"dev\",commit=\"\",model=\"Raspberry Pi 4 Model B Rev 1.4\",revision=\"c03114\",soc=\"BCM2711\",firmware=\"30aa0d70ab280427ba04ebc718c81d4350b9d394\"} 1\n" +
+		"# HELP rpi_clock_hertz Clock frequency in hertz.\n" +
+		"# TYPE rpi_clock_hertz gauge\n" +
+		"rpi_clock_hertz{clock=\"arm\"} 600000000\n" +
+		"rpi_clock_hertz{clock=\"core\"} 250000000\n" +
+		"rpi_clock_hertz{clock=\"dpi\"} 0\n" +
+		"rpi_clock_hertz{clock=\"emmc\"} 0\n" +
+		"rpi_clock_hertz{clock=\"h264\"} 0\n" +
+		"rpi_clock_hertz{clock=\"hdmi\"} 0\n" +
+		"rpi_clock_hertz{clock=\"isp\"} 0\n" +
+		"rpi_clock_hertz{clock=\"pixel\"} 0\n" +
+		"rpi_clock_hertz{clock=\"pwm\"} 0\n" +
+		"rpi_clock_hertz{clock=\"uart\"} 0\n" +
+		"rpi_clock_hertz{clock=\"v3d\"} 0\n" +
+		"rpi_clock_hertz{clock=\"vec\"} 0\n" +
+		"# HELP rpi_temperature_celsius SoC temperature in degrees celsius.\n" +
+		"# TYPE rpi_temperature_celsius gauge\n" +
+		"rpi_temperature_celsius 45\n" +
+		"# HELP rpi_voltage_volts Voltage in volts.\n" +
+		"# TYPE rpi_voltage_volts gauge\n" +
+		"rpi_voltage_volts{rail=\"core\"} 1.35\n" +
+		"rpi_voltage_volts{rail=\"sdram_c\"} 1.2\n" +
+		"rpi_voltage_volts{rail=\"sdram_i\"} 1.2\n" +
+		"rpi_voltage_volts{rail=\"sdram_p\"} 1.225\n" +
+		"# HELP rpi_throttled Raw throttled bitfield.\n" +
+		"# TYPE rpi_throttled gauge\n" +
+		"rpi_throttled 327680\n" +
+		"# HELP rpi_throttled_flag Throttled flag, 1 if set.\n" +
+		"# TYPE rpi_throttled_flag gauge\n" +
+		"rpi_throttled_flag{flag=\"frequency_capped\"} 0\n" +
+		"rpi_throttled_flag{flag=\"frequency_capped_occurred\"} 0\n" +
+		"rpi_throttled_flag{flag=\"soft_temp_limit\"} 0\n" +
+		"rpi_throttled_flag{flag=\"soft_temp_limit_occurred\"} 0\n" +
+		"rpi_throttled_flag{flag=\"throttling\"} 0\n" +
+		"rpi_throttled_flag{flag=\"throttling_occurred\"} 1\n" +
+		"rpi_throttled_flag{flag=\"under_voltage\"} 0\n" +
+		"rpi_throttled_flag{flag=\"under_voltage_occurred\"} 1\n" +
+		"# HELP rpi_memory_bytes Memory split, firmware heaps and totals in bytes.\n" +
+		"# TYPE rpi_memory_bytes gauge\n" +
+		"rpi_memory_bytes{area=\"arm\"} 994050048\n" +
+		"rpi_memory_bytes{area=\"gpu\"} 79691776\n" +
+		"rpi_memory_bytes{area=\"malloc_total\"} 14680064\n" +
+		"rpi_memory_bytes{area=\"mem_available\"} 3398336512\n" +
+		"rpi_memory_bytes{area=\"mem_free\"} 2783277056\n" +
+		"rpi_memory_bytes{area=\"mem_total\"} 3977621504\n" +
+		"# HELP rpi_scrape_success Whether all samples of a collector succeeded.\n" +
+		"# TYPE rpi_scrape_success gauge\n" +
+		"rpi_scrape_success{collector=\"clock\"} 1\n" +
+		"rpi_scrape_success{collector=\"memory\"} 1\n" +
+		"rpi_scrape_success{collector=\"temperature\"} 1\n" +
+		"rpi_scrape_success{collector=\"throttled\"} 1\n" +
+		"rpi_scrape_success{collector=\"voltage\"} 1\n" +
+		"# HELP rpi_scrape_duration_seconds Duration of a collector in seconds.\n" +
+		"# TYPE rpi_scrape_duration_seconds gauge\n"
 
 	got := rr.Body.String()
-	got = strings.TrimSpace(got)
-	if got != expected {
+	if !strings.HasPrefix(got, expected) {
 		t.Errorf("handler returned unexpected body: got %v want %v",
 			rr.Body.String(), expected)
 	}
+	if !strings.Contains(got, "rpi_scrape_duration_seconds{collector=\"temperature\"} ") {
+		t.Errorf("handler returned no scrape duration: got %v", got)
+	}
+}
+
+func Test_MetricsReturnsOpenMetricsText(t *testing.T) {
+	req := httptest.NewRequest("GET", "/metrics", nil)
+	req.Header.Set("Accept", "application/openmetrics-text;version=1.0.0;q=0.5,text/plain;version=0.0.4;q=0.3,*/*;q=0.2")
+	rr := httptest.NewRecorder()
+
+	events := watcher.NewEvents(10)
+	last := time.UnixMilli(1700000000500)
+	events.Add(watcher.Event{Time: last, Bit: 0, Flag: "Undervoltage detected", Set: true})
+
+	Handler := Handle{Cmd: mockRunnerSuccess{}, Watcher: &watcher.Watcher{Events: events}}
+	handler := http.HandlerFunc(Handler.Metrics)
+	handler.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Errorf("handler returned wrong status code: got %v want %v",
+			status, http.StatusOK)
+	}
+	if contentType := rr.Header().Get("Content-Type"); contentType != "application/openmetrics-text; version=1.0.0; charset=utf-8" {
+		t.Errorf("handler returned wrong content type: got %v", contentType)
+	}
+
+	got := rr.Body.String()
+	created := fmt.Sprintf("%.3f", float64(events.Created().UnixMilli())/1000)
+	for _, expected := range []string{
+		"# TYPE rpi_clock_hertz gauge\n# UNIT rpi_clock_hertz hertz\n# HELP rpi_clock_hertz Clock frequency in hertz.\n",
+		"# TYPE rpi_temperature_celsius gauge\n# UNIT rpi_temperature_celsius celsius\n",
+		"# TYPE rpi_throttled_transitions counter\n# HELP rpi_throttled_transitions Observed throttled flag transitions.\n",
+		"rpi_throttled_transitions_total{flag=\"under_voltage\",state=\"set\"} 1 # {} 1 1700000000.500\n",
+		"rpi_throttled_transitions_created{flag=\"under_voltage\",state=\"set\"} " + created + "\n",
+		"rpi_throttled_transitions_total{flag=\"under_voltage\",state=\"cleared\"} 0\n",
+	} {
+		if !strings.Contains(got, expected) {
+			t.Errorf("handler returned unexpected body: got %v want %v",
+				got, expected)
+		}
+	}
+	if !strings.HasSuffix(got, "\n# EOF\n") {
+		t.Errorf("handler returned body without EOF: got %v", got)
+	}
+}
+
+func Test_MetricsNegotiatesFormat(t *testing.T) {
+	tests := map[string]bool{
+		"":                             false,
+		"*/*":                          false,
+		"text/plain":                   false,
+		"application/openmetrics-text": true,
+		"application/openmetrics-text;q=0.2, text/plain;q=0.8": false,
+		"text/plain;q=0.2, application/openmetrics-text;q=0.8": true,
+		"application/openmetrics-text;q=0":                     false,
+	}
+
+	for accept, expected := range tests {
+		if got := openMetrics(accept); got != expected {
+			t.Errorf("openMetrics(%q) returned %v want %v", accept, got, expected)
+		}
+	}
+
+	req := httptest.NewRequest("GET", "/metrics", nil)
+	rr := httptest.NewRecorder()
+
+	Handler := Handle{Cmd: mockRunnerSuccess{}}
+	handler := http.HandlerFunc(Handler.Metrics)
+	handler.ServeHTTP(rr, req)
+
+	if contentType := rr.Header().Get("Content-Type"); contentType != "text/plain; version=0.0.4; charset=utf-8" {
+		t.Errorf("handler returned wrong content type: got %v", contentType)
+	}
+	if strings.Contains(rr.Body.String(), "# EOF") || strings.Contains(rr.Body.String(), "# UNIT") {
+		t.Errorf("handler returned OpenMetrics: got %v", rr.Body.String())
+	}
+}
+
+// mockRunnerPartial fails the commands starting with fail, e.g.
+// measure_temp or codec_enabled MPG2.
+type mockRunnerPartial struct {
+	fail string
+}
+
+func (m mockRunnerPartial) Run(args ...string) (map[string]string, error) {
+	if strings.HasPrefix(strings.Join(args, " "), m.fail) {
+		return nil, fmt.Errorf("command failed")
+	}
+	return mockRunnerSuccess{}.Run(args...)
+}
+
+func (m mockRunnerPartial) RunContext(ctx context.Context, args ...string) (map[string]string, error) {
+	return m.Run(args...)
+}
+
+func (m mockRunnerPartial) RunRaw(ctx context.Context, args ...string) (string, error) {
+	if strings.HasPrefix(strings.Join(args, " "), m.fail) {
+		return "", fmt.Errorf("command failed")
+	}
+	return mockRunnerSuccess{}.RunRaw(ctx, args...)
+}
+
+func Test_MetricsOmitsFailedSamples(t *testing.T) {
+	req := httptest.NewRequest("GET", "/metrics", nil)
+	rr := httptest.NewRecorder()
+
+	Handler := Handle{Cmd: mockRunnerPartial{fail: "measure_temp"}}
+	handler := http.HandlerFunc(Handler.Metrics)
+	handler.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Errorf("handler returned wrong status code: got %v want %v",
+			status, http.StatusOK)
+	}
+
+	got := rr.Body.String()
+	if strings.Contains(got, "rpi_temperature_celsius") {
+		t.Errorf("handler returned failed sample: got %v", got)
+	}
+	for _, expected := range []string{
+		"rpi_scrape_success{collector=\"temperature\"} 0\n",
+		"rpi_scrape_success{collector=\"clock\"} 1\n",
+		"rpi_clock_hertz{clock=\"arm\"} 600000000\n",
+	} {
+		if !strings.Contains(got, expected) {
+			t.Errorf("handler returned unexpected body: got %v want %v",
+				got, expected)
+		}
+	}
+
+	req = httptest.NewRequest("GET", "/metrics", nil)
+	rr = httptest.NewRecorder()
+
+	Handler = Handle{Cmd: mockRunnerError{}, MetricsLegacy: true}
+	handler = http.HandlerFunc(Handler.Metrics)
+	handler.ServeHTTP(rr, req)
+
+	got = rr.Body.String()
+	for _, unexpected := range []string{"rpi_clock", "rpi_voltage", "rpi_temperature", "rpi_throttled"} {
+		if strings.Contains(got, unexpected) {
+			t.Errorf("handler returned failed sample: got %v", got)
+		}
+	}
+	if !strings.Contains(got, "rpi_scrape_success{collector=\"voltage\"} 0\n") {
+		t.Errorf("handler returned no scrape success: got %v", got)
+	}
+}
+
+func Test_MetricsReturnsLegacyNames(t *testing.T) {
+	req := httptest.NewRequest("GET", "/metrics", nil)
+	rr := httptest.NewRecorder()
+
+	Handler := Handle{Cmd: mockRunnerSuccess{}, MetricsLegacy: true}
+	handler := http.HandlerFunc(Handler.Metrics)
+	handler.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Errorf("handler returned wrong status code: got %v want %v",
+			status, http.StatusOK)
+	}
+
+	expected := "rpi_clock_arm 600000000\nrpi_clock_core 250000000\nrpi_clock_dpi 0\n" +
+		"rpi_clock_emmc 0\nrpi_clock_h264 0\nrpi_clock_hdmi 0\n" +
+		"rpi_clock_isp 0\nrpi_clock_pixel 0\nrpi_clock_pwm 0\n" +
+		"rpi_clock_uart 0\nrpi_clock_v3d 0\nrpi_clock_vec 0\n" +
+		"rpi_temperature 45\nrpi_voltage_core 1.35\nrpi_voltage_sdram_c 1.2\n" +
+		"rpi_voltage_sdram_i 1.2\nrpi_voltage_sdram_p 1.225"
+
+	got := rr.Body.String()
+	got = strings.TrimSpace(got)
+	if !strings.HasSuffix(got, expected) {
+		t.Errorf("handler returned unexpected body: got %v want %v",
+			rr.Body.String(), expected)
+	}
+}
+
+func Test_MetricsReturnsThrottledFlagsAndTransitions(t *testing.T) {
+	req := httptest.NewRequest("GET", "/metrics", nil)
+	rr := httptest.NewRecorder()
+
+	events := watcher.NewEvents(10)
+	events.Add(
+		watcher.Event{Time: time.Now(), Bit: 0, Flag: "Undervoltage detected", Set: true},
+		watcher.Event{Time: time.Now(), Bit: 0, Flag: "Undervoltage detected", Set: false},
+		watcher.Event{Time: time.Now(), Bit: 0, Flag: "Undervoltage detected", Set: true},
+	)
+
+	Handler := Handle{Cmd: mockRunnerSuccess{}, Watcher: &watcher.Watcher{Events: events}}
+	handler := http.HandlerFunc(Handler.Metrics)
+	handler.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Errorf("handler returned wrong status code: got %v want %v",
+			status, http.StatusOK)
+	}
+
+	got := rr.Body.String()
+	for _, expected := range []string{
+		"rpi_throttled 327680\n",
+		"rpi_throttled_flag{flag=\"under_voltage\"} 0\n",
+		"rpi_throttled_flag{flag=\"under_voltage_occurred\"} 1\n",
+		"rpi_throttled_flag{flag=\"throttling_occurred\"} 1\n",
+		"rpi_throttled_transitions_total{flag=\"under_voltage\",state=\"set\"} 2\n",
+		"rpi_throttled_transitions_total{flag=\"under_voltage\",state=\"cleared\"} 1\n",
+		"rpi_throttled_transitions_total{flag=\"throttling\",state=\"set\"} 0\n",
+	} {
+		if !strings.Contains(got, expected) {
+			t.Errorf("handler returned unexpected body: got %v want %v",
+				got, expected)
+		}
+	}
+}
+
+func historySampler() *sampler.Sampler {
+	s := &sampler.Sampler{History: sampler.NewHistory(10)}
+	now := time.Now()
+	for i, temp := range []float64{40, 42, 44, 50} {
+		s.History.Add(sampler.Snapshot{
+			Time:        now.Add(time.Duration(i-4) * time.Minute),
+			Temperature: vcgencmd.Temperature(temp),
+		})
+	}
+
+	return s
+}
+
+func Test_HistoryReturnsJSON(t *testing.T) {
+	req := httptest.NewRequest("GET", "/history/temp?since=5m&step=1h", nil)
+	req = mux.SetURLVars(req, map[string]string{"metric": "temp"})
+	rr := httptest.NewRecorder()
+
+	Handler := Handle{Sampler: historySampler()}
+	handler := http.HandlerFunc(Handler.History)
+	handler.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Errorf("handler returned wrong status code: got %v want %v",
+			status, http.StatusOK)
+	}
+
+	var got struct {
+		Metric string          `json:"metric"`
+		Unit   string          `json:"unit"`
+		Step   string          `json:"step"`
+		Points []sampler.Point `json:"points"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &got); err != nil {
+		t.Fatalf("handler returned invalid JSON: %v", err)
+	}
+	if got.Metric != "temp" || got.Unit != "celsius" || got.Step != "1h0m0s" {
+		t.Errorf("handler returned unexpected body: got %v", rr.Body.String())
+	}
+	if len(got.Points) != 1 || got.Points[0].Min != 40 || got.Points[0].Max != 50 ||
+		got.Points[0].Avg != 44 || got.Points[0].Count != 4 {
+		t.Errorf("handler returned unexpected points: got %v", got.Points)
+	}
+}
+
+func Test_HistoryReturnsNotFoundIfMetricIsUnknown(t *testing.T) {
+	req := httptest.NewRequest("GET", "/history/humidity", nil)
+	req = mux.SetURLVars(req, map[string]string{"metric": "humidity"})
+	rr := httptest.NewRecorder()
+
+	Handler := Handle{Sampler: historySampler()}
+	handler := http.HandlerFunc(Handler.History)
+	handler.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusNotFound {
+		t.Errorf("handler returned wrong status code: got %v want %v",
+			status, http.StatusNotFound)
+	}
+}
+
+func Test_HistoryReturnsBadRequestIfQueryIsInvalid(t *testing.T) {
+	for _, query := range []string{"since=yesterday", "until=now", "step=0s", "step=fast", "since=1h&until=2h"} {
+		req := httptest.NewRequest("GET", "/history/temp?"+query, nil)
+		req = mux.SetURLVars(req, map[string]string{"metric": "temp"})
+		rr := httptest.NewRecorder()
+
+		Handler := Handle{Sampler: historySampler()}
+		handler := http.HandlerFunc(Handler.History)
+		handler.ServeHTTP(rr, req)
+
+		if status := rr.Code; status != http.StatusBadRequest {
+			t.Errorf("handler returned wrong status code for %s: got %v want %v",
+				query, status, http.StatusBadRequest)
+		}
+	}
+}
+
+func Test_StreamPushesServerSentEvents(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	s := &sampler.Sampler{Cmd: mockRunnerSuccess{}, Interval: 10 * time.Millisecond}
+	go s.Run(ctx)
+
+	Handler := Handle{Sampler: s}
+	server := httptest.NewServer(http.HandlerFunc(Handler.Stream))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "?interval=20ms")
+	if err != nil {
+		t.Fatalf("failed to connect stream: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if status := resp.StatusCode; status != http.StatusOK {
+		t.Errorf("handler returned wrong status code: got %v want %v",
+			status, http.StatusOK)
+	}
+	expected := "text/event-stream"
+	if contentType := resp.Header.Get("Content-Type"); contentType != expected {
+		t.Errorf("handler returned wrong content type: got %v want %v",
+			contentType, expected)
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	var lines []string
+	for len(lines) < 3 && scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if len(lines) != 3 || lines[0] != "event: snapshot" || !strings.HasPrefix(lines[1], "id: ") ||
+		!strings.HasPrefix(lines[2], "data: ") {
+		t.Fatalf("handler returned unexpected event: got %v", lines)
+	}
+
+	var snapshot map[string]any
+	if err := json.Unmarshal([]byte(strings.TrimPrefix(lines[2], "data: ")), &snapshot); err != nil {
+		t.Fatalf("handler returned invalid event data: %v", err)
+	}
+	for _, key := range []string{"time", "temp", "volt", "clock", "throttled"} {
+		if _, ok := snapshot[key]; !ok {
+			t.Errorf("handler returned event data without %s: got %v", key, lines[2])
+		}
+	}
+}
+
+func Test_StreamReturnsBadRequestIfIntervalIsInvalid(t *testing.T) {
+	req := httptest.NewRequest("GET", "/stream?interval=often", nil)
+	rr := httptest.NewRecorder()
+
+	Handler := Handle{Sampler: &sampler.Sampler{Interval: time.Second}}
+	handler := http.HandlerFunc(Handler.Stream)
+	handler.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusBadRequest {
+		t.Errorf("handler returned wrong status code: got %v want %v",
+			status, http.StatusBadRequest)
+	}
+}
+
+func wsServer(t *testing.T, limit int) (*httptest.Server, string) {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	s := &sampler.Sampler{Cmd: mockRunnerSuccess{}, Interval: 10 * time.Millisecond}
+	go s.Run(ctx)
+
+	Handler := Handle{Sampler: s, Subscribers: NewLimiter(limit)}
+	server := httptest.NewServer(middleware.Authorization(true, "secret", Handler.WebSocket))
+	t.Cleanup(server.Close)
+
+	return server, "ws" + strings.TrimPrefix(server.URL, "http")
+}
+
+func wsDial(t *testing.T, url string) *websocket.Conn {
+	header := http.Header{"Authorization": []string{"Bearer secret"}}
+	conn, _, err := websocket.DefaultDialer.Dial(url, header)
+	if err != nil {
+		t.Fatalf("failed to dial websocket: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return conn
+}
+
+func Test_WebSocketPushesSubscribedReadings(t *testing.T) {
+	_, url := wsServer(t, 1)
+	conn := wsDial(t, url)
+
+	var msg map[string]any
+	if err := conn.WriteJSON(map[string]any{"action": "subscribe", "metrics": []string{"clock.arm", "temp"}}); err != nil {
+		t.Fatalf("failed to subscribe: %v", err)
+	}
+	if err := conn.ReadJSON(&msg); err != nil {
+		t.Fatalf("failed to read reply: %v", err)
+	}
+	if msg["type"] != "subscriptions" || fmt.Sprint(msg["metrics"]) != "[clock.arm temp]" {
+		t.Errorf("handler returned unexpected reply: got %v", msg)
+	}
+
+	msg = nil
+	if err := conn.ReadJSON(&msg); err != nil {
+		t.Fatalf("failed to read readings: %v", err)
+	}
+	expected := map[string]any{"clock.arm": 600000000.0, "temp": 45.0}
+	if msg["type"] != "readings" || fmt.Sprint(msg["readings"]) != fmt.Sprint(expected) {
+		t.Errorf("handler returned unexpected readings: got %v want %v", msg, expected)
+	}
+
+	if err := conn.WriteJSON(map[string]any{"action": "unsubscribe", "metrics": []string{"temp"}}); err != nil {
+		t.Fatalf("failed to unsubscribe: %v", err)
+	}
+	for {
+		msg = nil
+		if err := conn.ReadJSON(&msg); err != nil {
+			t.Fatalf("failed to read reply: %v", err)
+		}
+		if msg["type"] == "subscriptions" {
+			break
+		}
+	}
+	if fmt.Sprint(msg["metrics"]) != "[clock.arm]" {
+		t.Errorf("handler returned unexpected reply: got %v", msg)
+	}
+}
+
+func Test_WebSocketReturnsErrorIfMessageIsInvalid(t *testing.T) {
+	_, url := wsServer(t, 1)
+	conn := wsDial(t, url)
+
+	messages := []any{
+		map[string]any{"action": "subscribe", "metrics": []string{"humidity"}},
+		map[string]any{"action": "publish", "metrics": []string{"temp"}},
+		"subscribe temp",
+	}
+	details := []string{"unknown metric: humidity", "unknown action: publish", "invalid message"}
+
+	for i, m := range messages {
+		if err := conn.WriteJSON(m); err != nil {
+			t.Fatalf("failed to write message: %v", err)
+		}
+
+		var msg map[string]any
+		if err := conn.ReadJSON(&msg); err != nil {
+			t.Fatalf("failed to read reply: %v", err)
+		}
+		if msg["type"] != "error" || msg["detail"] != details[i] {
+			t.Errorf("handler returned unexpected reply: got %v want %v", msg, details[i])
+		}
+	}
+}
+
+func Test_WebSocketRejectsSubscribersAboveLimit(t *testing.T) {
+	_, url := wsServer(t, 1)
+	wsDial(t, url)
+
+	header := http.Header{"Authorization": []string{"Bearer secret"}}
+	_, resp, err := websocket.DefaultDialer.Dial(url, header)
+	if err == nil {
+		t.Fatalf("handler accepted subscriber above limit")
+	}
+	if status := resp.StatusCode; status != http.StatusServiceUnavailable {
+		t.Errorf("handler returned wrong status code: got %v want %v",
+			status, http.StatusServiceUnavailable)
+	}
+}
+
+func Test_WebSocketRejectsUpgradeWithoutToken(t *testing.T) {
+	_, url := wsServer(t, 1)
+
+	_, resp, err := websocket.DefaultDialer.Dial(url, nil)
+	if err == nil {
+		t.Fatalf("handler accepted subscriber without token")
+	}
+	if status := resp.StatusCode; status != http.StatusUnauthorized {
+		t.Errorf("handler returned wrong status code: got %v want %v",
+			status, http.StatusUnauthorized)
+	}
+}
+
+func Test_EventsReturnsJSON(t *testing.T) {
+	now := time.Now()
+	w := &watcher.Watcher{Events: watcher.NewEvents(10)}
+	w.Events.Add(
+		watcher.Event{Time: now.Add(-time.Hour), Bit: 0, Flag: "Undervoltage detected", Set: true},
+		watcher.Event{Time: now.Add(-time.Minute), Bit: 0, Flag: "Undervoltage detected", Set: false},
+	)
+
+	req := httptest.NewRequest("GET", "/events?since=10m", nil)
+	rr := httptest.NewRecorder()
+
+	Handler := Handle{Watcher: w}
+	handler := http.HandlerFunc(Handler.Events)
+	handler.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Errorf("handler returned wrong status code: got %v want %v",
+			status, http.StatusOK)
+	}
+
+	var got struct {
+		Events []watcher.Event `json:"events"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &got); err != nil {
+		t.Fatalf("handler returned invalid JSON: %v", err)
+	}
+	if len(got.Events) != 1 || got.Events[0].Set {
+		t.Errorf("handler returned unexpected events: got %v", rr.Body.String())
+	}
+}
+
+func Test_AlertsReturnsJSON(t *testing.T) {
+	e := &alert.Engine{Rules: []alert.Rule{{Name: "hot", Metric: "temp", Comparator: ">", Threshold: 80}}}
+	e.Evaluate(sampler.Snapshot{Time: time.Now(), Temperature: 81})
+
+	req := httptest.NewRequest("GET", "/alerts", nil)
+	rr := httptest.NewRecorder()
+
+	Handler := Handle{Alerting: e}
+	handler := http.HandlerFunc(Handler.Alerts)
+	handler.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Errorf("handler returned wrong status code: got %v want %v",
+			status, http.StatusOK)
+	}
+
+	var got struct {
+		Alerts []alert.Alert `json:"alerts"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &got); err != nil {
+		t.Fatalf("handler returned invalid JSON: %v", err)
+	}
+	if len(got.Alerts) != 1 || got.Alerts[0].State != alert.StateFiring {
+		t.Errorf("handler returned unexpected alerts: got %v", rr.Body.String())
+	}
+}
+
+func Test_InfluxReturnsLineProtocol(t *testing.T) {
+	req := httptest.NewRequest("GET", "/influx", nil)
+	rr := httptest.NewRecorder()
+
+	Handler := Handle{Cmd: mockRunnerSuccess{}, InfluxFormat: influx.Format{Measurement: "rpinfo", Tags: map[string]string{"host": "pi"}}}
+	handler := http.HandlerFunc(Handler.Influx)
+	handler.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Errorf("handler returned wrong status code: got %v want %v",
+			status, http.StatusOK)
+	}
+
+	got := rr.Body.String()
+	expected := "rpinfo,host=pi temp=45,volt.core=1.35,volt.sdram_c=1.2,volt.sdram_i=1.2,volt.sdram_p=1.225,clock.arm=600000000i,"
+	if !strings.HasPrefix(got, expected) {
+		t.Errorf("handler returned unexpected body: got %v want %v",
+			got, expected)
+	}
+	if !strings.Contains(got, ",throttled=327680i,") {
+		t.Errorf("handler returned unexpected body: got %v", got)
+	}
+}
+
+func Test_InfluxReturnsServerErrorIfCommandFails(t *testing.T) {
+	req := httptest.NewRequest("GET", "/influx", nil)
+	rr := httptest.NewRecorder()
+
+	Handler := Handle{Cmd: mockRunnerError{}, InfluxFormat: influx.Format{Measurement: "rpinfo"}}
+	handler := http.HandlerFunc(Handler.Influx)
+	handler.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusInternalServerError {
+		t.Errorf("handler returned wrong status code: got %v want %v",
+			status, http.StatusInternalServerError)
+	}
+}
+
+func Test_BoardReturnsJSON(t *testing.T) {
+	board.Root = t.TempDir()
+	os.MkdirAll(filepath.Join(board.Root, "proc/device-tree"), 0o755)
+	os.WriteFile(filepath.Join(board.Root, "proc/device-tree/model"), []byte("Raspberry Pi 5 Model B Rev 1.0\x00"), 0o644)
+	os.WriteFile(filepath.Join(board.Root, "proc/device-tree/serial-number"), []byte("abcdef0123456789\x00"), 0o644)
+	os.WriteFile(filepath.Join(board.Root, "proc/cpuinfo"), []byte("Revision\t: d04170\n"), 0o644)
+	defer func() { board.Root = "/" }()
+
+	req := httptest.NewRequest("GET", "/board", nil)
+	rr := httptest.NewRecorder()
+
+	Handler := Handle{}
+	handler := http.HandlerFunc(Handler.Board)
+	handler.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Errorf("handler returned wrong status code: got %v want %v",
+			status, http.StatusOK)
+	}
+	expected := `{"model":"Raspberry Pi 5 Model B Rev 1.0","serial":"abcdef0123456789","revision":"d04170","type":"5","soc":"BCM2712","memory":8589934592,"manufacturer":"Sony UK","pcb_revision":"1.0"}`
+	got := strings.TrimSpace(rr.Body.String())
+	if got != expected {
+		t.Errorf("handler returned unexpected body: got %v want %v",
+			got, expected)
+	}
+}
+
+func Test_FirmwareReturnsJSON(t *testing.T) {
+	req := httptest.NewRequest("GET", "/firmware", nil)
+	rr := httptest.NewRecorder()
+
+	Handler := Handle{Cmd: mockRunnerRaw{"version": firmwareVersion, "bootloader_version": bootloaderVersion, "bootloader_config": bootloaderConfig}}
+	handler := http.HandlerFunc(Handler.Firmware)
+	handler.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Errorf("handler returned wrong status code: got %v want %v",
+			status, http.StatusOK)
+	}
+	expected := `{"firmware":{"date":"2023-10-17T15:39:16Z","hash":"30aa0d70ab280427ba04ebc718c81d4350b9d394","variant":"start","flags":["clean","release"]},` +
+		`"bootloader":{"date":"2023-01-11T17:40:52Z","hash":"8ba17717fbcedd4c3b6d4bce7e50c7af4155cba9","flags":["release"],"timestamp":"2023-01-11T17:40:52Z","update_time":"2023-02-20T10:04:18Z","capabilities":"0x0000007f"},` +
+		`"bootloader_config":{"all":{"BOOT_ORDER":"0xf41","BOOT_UART":"0"}}}`
+	got := strings.TrimSpace(rr.Body.String())
+	if got != expected {
+		t.Errorf("handler returned unexpected body: got %v want %v",
+			got, expected)
+	}
+}
+
+func Test_FirmwareOmitsBootloaderIfUnsupported(t *testing.T) {
+	req := httptest.NewRequest("GET", "/firmware", nil)
+	rr := httptest.NewRecorder()
+
+	Handler := Handle{Cmd: mockRunnerRaw{"version": firmwareVersion}}
+	handler := http.HandlerFunc(Handler.Firmware)
+	handler.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Errorf("handler returned wrong status code: got %v want %v",
+			status, http.StatusOK)
+	}
+	expected := `{"firmware":{"date":"2023-10-17T15:39:16Z","hash":"30aa0d70ab280427ba04ebc718c81d4350b9d394","variant":"start","flags":["clean","release"]}}`
+	got := strings.TrimSpace(rr.Body.String())
+	if got != expected {
+		t.Errorf("handler returned unexpected body: got %v want %v",
+			got, expected)
+	}
+}
+
+func Test_FirmwareReturnsServerErrorIfBootloaderFails(t *testing.T) {
+	req := httptest.NewRequest("GET", "/firmware", nil)
+	rr := httptest.NewRecorder()
+
+	Handler := Handle{Cmd: mockRunnerFailingRaw{"version": firmwareVersion}}
+	handler := http.HandlerFunc(Handler.Firmware)
+	handler.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusInternalServerError {
+		t.Errorf("handler returned wrong status code: got %v want %v",
+			status, http.StatusInternalServerError)
+	}
+}
+
+func Test_FirmwareSetsCacheAge(t *testing.T) {
+	req := httptest.NewRequest("GET", "/firmware", nil)
+	rr := httptest.NewRecorder()
+
+	Handler := Handle{Cmd: vcgencmd.NewCache(mockRunnerRaw{"version": firmwareVersion}, time.Minute, nil)}
+	handler := http.HandlerFunc(Handler.Firmware)
+	handler.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Errorf("handler returned wrong status code: got %v want %v",
+			status, http.StatusOK)
+	}
+	if age := rr.Header().Get("X-Rpinfo-Cache-Age"); age != "0.000" {
+		t.Errorf("handler returned unexpected cache age: got %v want %v",
+			age, "0.000")
+	}
+}
+
+func Test_FirmwareReturnsServerErrorIfVersionFails(t *testing.T) {
+	req := httptest.NewRequest("GET", "/firmware", nil)
+	rr := httptest.NewRecorder()
+
+	Handler := Handle{Cmd: mockRunnerRaw{}}
+	handler := http.HandlerFunc(Handler.Firmware)
+	handler.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusInternalServerError {
+		t.Errorf("handler returned wrong status code: got %v want %v",
+			status, http.StatusInternalServerError)
+	}
+}
+
+func Test_FirmwareReturnsServerErrorIfRawIsUnsupported(t *testing.T) {
+	req := httptest.NewRequest("GET", "/firmware", nil)
+	rr := httptest.NewRecorder()
+
+	Handler := Handle{Cmd: mockRunnerError{}}
+	handler := http.HandlerFunc(Handler.Firmware)
+	handler.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusInternalServerError {
+		t.Errorf("handler returned wrong status code: got %v want %v",
+			status, http.StatusInternalServerError)
+	}
+}
+
+func Test_MemoryReturnsJSON(t *testing.T) {
+	board.Root = t.TempDir()
+	os.MkdirAll(filepath.Join(board.Root, "proc"), 0o755)
+	os.WriteFile(filepath.Join(board.Root, "proc/meminfo"), []byte(meminfo), 0o644)
+	defer func() { board.Root = "/" }()
+
+	req := httptest.NewRequest("GET", "/memory", nil)
+	rr := httptest.NewRecorder()
+
+	Handler := Handle{Cmd: mockRunnerSuccess{}}
+	handler := http.HandlerFunc(Handler.Memory)
+	handler.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Errorf("handler returned wrong status code: got %v want %v",
+			status, http.StatusOK)
+	}
+	expected := `{"arm":994050048,"gpu":79691776,"malloc_total":14680064,"mem_total":3977621504,"mem_free":2783277056,"mem_available":3398336512}`
+	got := strings.TrimSpace(rr.Body.String())
+	if got != expected {
+		t.Errorf("handler returned unexpected body: got %v want %v",
+			got, expected)
+	}
+}
+
+func Test_MemoryReturnsServerErrorIfCommandFails(t *testing.T) {
+	req := httptest.NewRequest("GET", "/memory", nil)
+	rr := httptest.NewRecorder()
+
+	Handler := Handle{Cmd: mockRunnerError{}}
+	handler := http.HandlerFunc(Handler.Memory)
+	handler.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusInternalServerError {
+		t.Errorf("handler returned wrong status code: got %v want %v",
+			status, http.StatusInternalServerError)
+	}
+}
+
+func Test_MemoryReturnsServerErrorIfHeapFails(t *testing.T) {
+	req := httptest.NewRequest("GET", "/memory", nil)
+	rr := httptest.NewRecorder()
+
+	Handler := Handle{Cmd: mockRunnerPartial{fail: "get_mem malloc_total"}}
+	handler := http.HandlerFunc(Handler.Memory)
+	handler.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusInternalServerError {
+		t.Errorf("handler returned wrong status code: got %v want %v",
+			status, http.StatusInternalServerError)
+	}
+}
+
+func Test_CodecsReturnsJSON(t *testing.T) {
+	req := httptest.NewRequest("GET", "/codecs", nil)
+	rr := httptest.NewRecorder()
+
+	Handler := Handle{Cmd: mockRunnerSuccess{}}
+	handler := http.HandlerFunc(Handler.Codecs)
+	handler.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Errorf("handler returned wrong status code: got %v want %v",
+			status, http.StatusOK)
+	}
+	expected := `{"codecs":{"H264":true,"MJPG":true,"MPG2":false,"MPG4":true,"WVC1":false},"unsupported":["WMV9","HEVC"]}`
+	got := strings.TrimSpace(rr.Body.String())
+	if got != expected {
+		t.Errorf("handler returned unexpected body: got %v want %v",
+			got, expected)
+	}
+}
+
+func Test_CodecsReturnsServerErrorIfCommandFails(t *testing.T) {
+	req := httptest.NewRequest("GET", "/codecs", nil)
+	rr := httptest.NewRecorder()
+
+	Handler := Handle{Cmd: mockRunnerError{}}
+	handler := http.HandlerFunc(Handler.Codecs)
+	handler.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusInternalServerError {
+		t.Errorf("handler returned wrong status code: got %v want %v",
+			status, http.StatusInternalServerError)
+	}
+}
+
+func Test_CodecsReturnsServerErrorIfACodecFails(t *testing.T) {
+	req := httptest.NewRequest("GET", "/codecs", nil)
+	rr := httptest.NewRecorder()
+
+	Handler := Handle{Cmd: mockRunnerPartial{fail: "codec_enabled MPG2"}}
+	handler := http.HandlerFunc(Handler.Codecs)
+	handler.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusInternalServerError {
+		t.Errorf("handler returned wrong status code: got %v want %v",
+			status, http.StatusInternalServerError)
+	}
+}
+
+func Test_CodecsReturnsGatewayTimeoutIfCommandHangs(t *testing.T) {
+	req := httptest.NewRequest("GET", "/codecs", nil)
+	rr := httptest.NewRecorder()
+
+	Handler := Handle{Cmd: mockRunnerHanging{}, Timeout: 10 * time.Millisecond}
+	handler := http.HandlerFunc(Handler.Codecs)
+	handler.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusGatewayTimeout {
+		t.Errorf("handler returned wrong status code: got %v want %v",
+			status, http.StatusGatewayTimeout)
+	}
 }
@@ -0,0 +1,104 @@
+/*
+Copyright (c) 2025 Tobias Schäfer. All rights reserved.
+Licensed under the MIT license, see LICENSE in the project root for details.
+*/
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/tschaefer/rpinfo/server/log"
+	"github.com/tschaefer/rpinfo/server/sampler"
+)
+
+type history struct {
+	Metric string          `json:"metric"`
+	Unit   string          `json:"unit,omitempty"`
+	Since  time.Time       `json:"since"`
+	Until  time.Time       `json:"until"`
+	Step   string          `json:"step,omitempty"`
+	Points []sampler.Point `json:"points"`
+}
+
+func (h Handle) History(w http.ResponseWriter, r *http.Request) {
+	metric := mux.Vars(r)["metric"]
+	if !slices.Contains(sampler.Metrics(), metric) {
+		go log.RequestWarn(r, http.StatusNotFound, fmt.Sprintf("unknown metric: %s", metric))
+		JSONError(w, http.StatusNotFound, "not found")
+		return
+	}
+
+	now := time.Now()
+	query := r.URL.Query()
+
+	since, err := parseTime(query.Get("since"), now, time.Time{})
+	if err != nil {
+		badRequest(w, r, fmt.Sprintf("invalid since: %v", err))
+		return
+	}
+	until, err := parseTime(query.Get("until"), now, now)
+	if err != nil {
+		badRequest(w, r, fmt.Sprintf("invalid until: %v", err))
+		return
+	}
+	if until.Before(since) {
+		badRequest(w, r, "until before since")
+		return
+	}
+
+	var step time.Duration
+	if value := query.Get("step"); value != "" {
+		if step, err = time.ParseDuration(value); err != nil || step <= 0 {
+			badRequest(w, r, fmt.Sprintf("invalid step: %s", value))
+			return
+		}
+	}
+
+	snapshots := h.Sampler.History.Range(since, until)
+	if since.IsZero() && len(snapshots) > 0 {
+		since = snapshots[0].Time
+	}
+
+	response := history{
+		Metric: metric,
+		Unit:   sampler.Unit(metric),
+		Since:  since,
+		Until:  until,
+		Points: sampler.Downsample(snapshots, metric, since, step),
+	}
+	if step > 0 {
+		response.Step = step.String()
+	}
+
+	go log.RequestInfo(r, http.StatusOK, "Fetched history")
+	json.NewEncoder(w).Encode(response)
+}
+
+// Parse an RFC 3339 timestamp or a duration relative to now, e.g. 1h for
+// one hour ago.
+func parseTime(value string, now, fallback time.Time) (time.Time, error) {
+	if value == "" {
+		return fallback, nil
+	}
+
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+
+	ago, err := time.ParseDuration(value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("neither RFC 3339 nor duration: %s", value)
+	}
+
+	return now.Add(-ago), nil
+}
+
+func badRequest(w http.ResponseWriter, r *http.Request, msg string) {
+	go log.RequestWarn(r, http.StatusBadRequest, msg)
+	JSONError(w, http.StatusBadRequest, msg)
+}
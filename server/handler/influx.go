@@ -0,0 +1,40 @@
+/*
+Copyright (c) 2025 Tobias Schäfer. All rights reserved.
+Licensed under the MIT license, see LICENSE in the project root for details.
+*/
+package handler
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/tschaefer/rpinfo/server/log"
+	"github.com/tschaefer/rpinfo/server/sampler"
+)
+
+// Snapshot collects the temperature, voltages, clocks and throttled status
+// like the sampler, e.g. for pushing them.
+func (h Handle) Snapshot(ctx context.Context) (sampler.Snapshot, error) {
+	snapshot, _, err := h.snapshot(ctx)
+	return snapshot, err
+}
+
+func (h Handle) snapshot(ctx context.Context) (sampler.Snapshot, time.Duration, error) {
+	e := &execer{h: h}
+	snapshot, err := sampler.Collect(ctx, e)
+	return snapshot, e.age, err
+}
+
+func (h Handle) Influx(w http.ResponseWriter, r *http.Request) {
+	snapshot, age, err := h.snapshot(r.Context())
+	if err != nil {
+		serverError(w, r, err)
+		return
+	}
+
+	h.cacheAge(w, age)
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	go log.RequestInfo(r, http.StatusOK, "Served influx")
+	w.Write(h.InfluxFormat.Line(snapshot))
+}
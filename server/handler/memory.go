@@ -0,0 +1,62 @@
+/*
+Copyright (c) 2025 Tobias Schäfer. All rights reserved.
+Licensed under the MIT license, see LICENSE in the project root for details.
+*/
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/tschaefer/rpinfo/server/board"
+	"github.com/tschaefer/rpinfo/server/log"
+	"github.com/tschaefer/rpinfo/vcgencmd"
+)
+
+// Sizes in bytes, the firmware heaps and the totals of /proc/meminfo are
+// omitted if unsupported.
+type memory struct {
+	ARM          uint64 `json:"arm"`
+	GPU          uint64 `json:"gpu"`
+	MallocTotal  uint64 `json:"malloc_total,omitempty"`
+	RelocTotal   uint64 `json:"reloc_total,omitempty"`
+	MemTotal     uint64 `json:"mem_total,omitempty"`
+	MemFree      uint64 `json:"mem_free,omitempty"`
+	MemAvailable uint64 `json:"mem_available,omitempty"`
+}
+
+func (h Handle) Memory(w http.ResponseWriter, r *http.Request) {
+	e := &execer{h: h}
+	var response memory
+	for area, size := range map[string]*uint64{"arm": &response.ARM, "gpu": &response.GPU} {
+		value, err := vcgencmd.GetMem(r.Context(), e, area)
+		if err != nil {
+			serverError(w, r, err)
+			return
+		}
+		*size = value
+	}
+
+	for area, size := range map[string]*uint64{"malloc_total": &response.MallocTotal, "reloc_total": &response.RelocTotal} {
+		value, err := vcgencmd.GetMem(r.Context(), e, area)
+		switch {
+		case err == nil:
+			*size = value
+		case errors.Is(err, vcgencmd.ErrCommandUnsupported), errors.Is(err, vcgencmd.ErrInvalidArguments):
+		default:
+			serverError(w, r, err)
+			return
+		}
+	}
+	h.cacheAge(w, e.age)
+
+	if meminfo, err := board.Meminfo(); err == nil {
+		response.MemTotal = meminfo["MemTotal"]
+		response.MemFree = meminfo["MemFree"]
+		response.MemAvailable = meminfo["MemAvailable"]
+	}
+
+	go log.RequestInfo(r, http.StatusOK, "Fetched memory")
+	json.NewEncoder(w).Encode(response)
+}
@@ -7,47 +7,72 @@ package handler
 
 import (
 	"bytes"
+	"context"
 	"fmt"
-	"iter"
-	"maps"
+	"mime"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/VictoriaMetrics/metrics"
+	"github.com/tschaefer/rpinfo/server/board"
 	"github.com/tschaefer/rpinfo/server/log"
 	"github.com/tschaefer/rpinfo/vcgencmd"
 	"github.com/tschaefer/rpinfo/version"
 )
 
-var (
-	rpi = metrics.NewSet()
+const (
+	contentTypeText        = "text/plain; version=0.0.4; charset=utf-8"
+	contentTypeOpenMetrics = "application/openmetrics-text; version=1.0.0; charset=utf-8"
 )
 
-func Metrics(w http.ResponseWriter, r *http.Request) {
-	clocks := []string{
-		"arm", "core", "dpi", "emmc", "h264",
-		"hdmi", "isp", "pixel", "pwm", "uart", "v3d", "vec",
-	}
-	for _, c := range clocks {
-		name := fmt.Sprintf(`rpi_clock_%s`, c)
-		rpi.GetOrCreateGauge(name, func() float64 { return clock(c) })
-	}
+// family is a metric family written with its metadata. Counters are
+// written by hand for their created timestamps and exemplars.
+type family struct {
+	name     string
+	help     string
+	kind     string
+	unit     string
+	set      *metrics.Set
+	counters []counter
+}
 
-	rpi.GetOrCreateGauge(`rpi_temperature`, func() float64 { return temperature() })
+// counter is a single sample of a counter family
+type counter struct {
+	labels   string
+	value    uint64
+	created  time.Time
+	exemplar time.Time
+}
 
-	voltages := []string{
-		"core", "sdram_c", "sdram_i", "sdram_p",
-	}
-	for _, v := range voltages {
-		name := fmt.Sprintf(`rpi_voltage_%s`, v)
-		rpi.GetOrCreateGauge(name, func() float64 { return voltage(v) })
+// result is the output of a command run by a scrape
+type result struct {
+	out map[string]string
+	err error
+}
+
+// scrape runs the commands of a single metrics request
+type scrape struct {
+	h       Handle
+	ctx     context.Context
+	age     time.Duration
+	results map[string]result
+}
+
+func (h Handle) Metrics(w http.ResponseWriter, r *http.Request) {
+	om := openMetrics(r.Header.Get("Accept"))
+	buffer, age := h.gather(r.Context(), om)
+	if om {
+		w.Header().Set("Content-Type", contentTypeOpenMetrics)
+	} else {
+		w.Header().Set("Content-Type", contentTypeText)
 	}
 
 	w.Header().Set("X-Rpinfo-Commit", version.Commit())
 	w.Header().Set("X-Rpinfo-Version", version.Release())
 
-	var buffer bytes.Buffer
-	rpi.WritePrometheus(&buffer)
+	h.cacheAge(w, age)
 	if _, err := w.Write(buffer.Bytes()); err != nil {
 
 		go log.RequestError(r, http.StatusInternalServerError, fmt.Sprintf("Failed to write metrics: %v", err))
@@ -57,59 +82,318 @@ func Metrics(w http.ResponseWriter, r *http.Request) {
 	go log.RequestInfo(r, http.StatusOK, "Served metrics")
 }
 
-func clock(kind string) float64 {
-	raw := exec("measure_clock", kind)
-	if raw == nil {
-		return 0.0
+// Gather scrapes the metrics in the Prometheus text format as served by
+// /metrics, e.g. for pushing them.
+func (h Handle) Gather(ctx context.Context) []byte {
+	buffer, _ := h.gather(ctx, false)
+	return buffer.Bytes()
+}
+
+func (h Handle) gather(ctx context.Context, om bool) (*bytes.Buffer, time.Duration) {
+	s := &scrape{h: h, ctx: ctx, results: make(map[string]result)}
+
+	var buffer bytes.Buffer
+	for _, f := range s.families() {
+		f.write(&buffer, om)
+	}
+	if h.MetricsLegacy {
+		s.legacy().WritePrometheus(&buffer)
+	}
+	if om {
+		buffer.WriteString("# EOF\n")
+	}
+
+	return &buffer, s.age
+}
+
+// openMetrics reports whether the Accept header prefers OpenMetrics over
+// the Prometheus text format.
+func openMetrics(accept string) bool {
+	om, text := -1.0, -1.0
+	for part := range strings.SplitSeq(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(part)
+		if err != nil {
+			continue
+		}
+
+		q := 1.0
+		if value, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(value, 64); err != nil {
+				continue
+			}
+		}
+
+		switch mediaType {
+		case "application/openmetrics-text":
+			om = max(om, q)
+		case "text/plain", "text/*", "*/*":
+			text = max(text, q)
+		}
+	}
+
+	return om > 0 && om >= text
+}
+
+// write writes the family in the Prometheus text format or in OpenMetrics,
+// the latter with unit metadata, created timestamps and exemplars.
+func (f family) write(w *bytes.Buffer, om bool) {
+	if f.set == nil && len(f.counters) == 0 || f.set != nil && len(f.set.ListMetricNames()) == 0 {
+		return
+	}
+
+	name := f.name
+	if f.kind == "counter" && !om {
+		name += "_total"
+	}
+	if om {
+		fmt.Fprintf(w, "# TYPE %s %s\n", name, f.kind)
+		if f.unit != "" {
+			fmt.Fprintf(w, "# UNIT %s %s\n", name, f.unit)
+		}
+		fmt.Fprintf(w, "# HELP %s %s\n", name, f.help)
+	} else {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, f.help, name, f.kind)
+	}
+
+	if f.set != nil {
+		f.set.WritePrometheus(w)
+	}
+	for _, c := range f.counters {
+		fmt.Fprintf(w, "%s_total{%s} %d", f.name, c.labels, c.value)
+		if om && !c.exemplar.IsZero() {
+			fmt.Fprintf(w, " # {} 1 %s", timestamp(c.exemplar))
+		}
+		w.WriteString("\n")
+		if om {
+			fmt.Fprintf(w, "%s_created{%s} %s\n", f.name, c.labels, timestamp(c.created))
+		}
+	}
+}
+
+// timestamp formats t in seconds as used by OpenMetrics
+func timestamp(t time.Time) string {
+	return strconv.FormatFloat(float64(t.UnixMilli())/1000, 'f', 3, 64)
+}
+
+// families returns the metric families of a fresh scrape. A failed
+// sample is omitted and reported by rpi_scrape_success of its collector.
+func (s *scrape) families() []family {
+	info := family{name: "rpi_info", help: "Information about rpinfo and the board.", kind: "gauge", set: metrics.NewSet()}
+	rpi := board.Read()
+	info.set.GetOrCreateGauge(fmt.Sprintf(`rpi_info{version=%q,commit=%q,model=%q,revision=%q,soc=%q,firmware=%q}`,
+		version.Release(), version.Commit(), rpi.Model, rpi.Revision, rpi.SoC, s.h.FirmwareHash), nil).Set(1)
+
+	success := family{name: "rpi_scrape_success", help: "Whether all samples of a collector succeeded.", kind: "gauge", set: metrics.NewSet()}
+	duration := family{name: "rpi_scrape_duration_seconds", help: "Duration of a collector in seconds.", kind: "gauge", unit: "seconds", set: metrics.NewSet()}
+	collect := func(collector string, samples func() bool) {
+		start := time.Now()
+		ok := samples()
+		success.set.GetOrCreateGauge(fmt.Sprintf(`rpi_scrape_success{collector=%q}`, collector), nil).Set(boolValue(ok))
+		duration.set.GetOrCreateGauge(fmt.Sprintf(`rpi_scrape_duration_seconds{collector=%q}`, collector), nil).Set(time.Since(start).Seconds())
 	}
 
-	value := func() string {
-		next, stop := iter.Pull(maps.Values(raw))
-		defer stop()
+	clock := family{name: "rpi_clock_hertz", help: "Clock frequency in hertz.", kind: "gauge", unit: "hertz", set: metrics.NewSet()}
+	collect("clock", func() bool {
+		ok := true
+		for _, c := range vcgencmd.Clocks {
+			value, err := s.clock(c)
+			if err != nil {
+				ok = false
+				continue
+			}
+			clock.set.GetOrCreateGauge(fmt.Sprintf(`rpi_clock_hertz{clock=%q}`, c), nil).Set(value)
+		}
+		return ok
+	})
+
+	temperature := family{name: "rpi_temperature_celsius", help: "SoC temperature in degrees celsius.", kind: "gauge", unit: "celsius", set: metrics.NewSet()}
+	collect("temperature", func() bool {
+		value, err := s.temperature()
+		if err != nil {
+			return false
+		}
+		temperature.set.GetOrCreateGauge(`rpi_temperature_celsius`, nil).Set(value)
+		return true
+	})
+
+	voltage := family{name: "rpi_voltage_volts", help: "Voltage in volts.", kind: "gauge", unit: "volts", set: metrics.NewSet()}
+	collect("voltage", func() bool {
+		ok := true
+		for _, v := range vcgencmd.Rails {
+			value, err := s.voltage(v)
+			if err != nil {
+				ok = false
+				continue
+			}
+			voltage.set.GetOrCreateGauge(fmt.Sprintf(`rpi_voltage_volts{rail=%q}`, v), nil).Set(value)
+		}
+		return ok
+	})
+
+	throttled := family{name: "rpi_throttled", help: "Raw throttled bitfield.", kind: "gauge", set: metrics.NewSet()}
+	flag := family{name: "rpi_throttled_flag", help: "Throttled flag, 1 if set.", kind: "gauge", set: metrics.NewSet()}
+	collect("throttled", func() bool {
+		value, err := s.throttled()
+		if err != nil {
+			return false
+		}
+		throttled.set.GetOrCreateGauge(`rpi_throttled`, nil).Set(float64(value))
+		for _, f := range vcgencmd.ThrottledFlags {
+			flag.set.GetOrCreateGauge(fmt.Sprintf(`rpi_throttled_flag{flag=%q}`, f.Name), nil).Set(boolValue(value.Has(1 << f.Bit)))
+		}
+		return true
+	})
 
-		v, _ := next()
-		return v
+	memory := family{name: "rpi_memory_bytes", help: "Memory split, firmware heaps and totals in bytes.", kind: "gauge", unit: "bytes", set: metrics.NewSet()}
+	collect("memory", func() bool {
+		ok := true
+		for _, area := range vcgencmd.MemoryAreas {
+			value, err := s.memory(area)
+			if err != nil {
+				ok = false
+				continue
+			}
+			memory.set.GetOrCreateGauge(fmt.Sprintf(`rpi_memory_bytes{area=%q}`, area), nil).Set(value)
+		}
+		for _, heap := range vcgencmd.FirmwareHeaps {
+			if value, err := s.memory(heap); err == nil {
+				memory.set.GetOrCreateGauge(fmt.Sprintf(`rpi_memory_bytes{area=%q}`, heap), nil).Set(value)
+			}
+		}
+
+		meminfo, err := board.Meminfo()
+		if err != nil {
+			return false
+		}
+		for area, field := range meminfoAreas {
+			if value, found := meminfo[field]; found {
+				memory.set.GetOrCreateGauge(fmt.Sprintf(`rpi_memory_bytes{area=%q}`, area), nil).Set(float64(value))
+			}
+		}
+		return ok
+	})
+
+	families := []family{info, clock, temperature, voltage, throttled, flag, memory}
+
+	if s.h.Watcher != nil {
+		events := s.h.Watcher.Events
+		transitions := family{name: "rpi_throttled_transitions", help: "Observed throttled flag transitions.", kind: "counter"}
+		for _, f := range vcgencmd.ThrottledFlags {
+			for _, state := range []string{"set", "cleared"} {
+				transitions.counters = append(transitions.counters, counter{
+					labels:   fmt.Sprintf(`flag=%q,state=%q`, f.Name, state),
+					value:    events.Count(f.Bit, state == "set"),
+					created:  events.Created(),
+					exemplar: events.Last(f.Bit, state == "set"),
+				})
+			}
+		}
+		families = append(families, transitions)
 	}
-	frequency, err := strconv.ParseFloat(value(), 64)
+
+	return append(families, success, duration)
+}
+
+// legacy returns the metrics named by reading, e.g. rpi_clock_arm, kept for
+// existing dashboards. It reuses the command results of the scrape.
+func (s *scrape) legacy() *metrics.Set {
+	rpi := metrics.NewSet()
+
+	for _, c := range vcgencmd.Clocks {
+		if value, err := s.clock(c); err == nil {
+			rpi.GetOrCreateGauge(fmt.Sprintf(`rpi_clock_%s`, c), nil).Set(value)
+		}
+	}
+
+	if value, err := s.temperature(); err == nil {
+		rpi.GetOrCreateGauge(`rpi_temperature`, nil).Set(value)
+	}
+
+	for _, v := range vcgencmd.Rails {
+		if value, err := s.voltage(v); err == nil {
+			rpi.GetOrCreateGauge(fmt.Sprintf(`rpi_voltage_%s`, v), nil).Set(value)
+		}
+	}
+
+	return rpi
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// meminfoAreas are the memory areas of the /proc/meminfo fields
+var meminfoAreas = map[string]string{
+	"mem_total":     "MemTotal",
+	"mem_free":      "MemFree",
+	"mem_available": "MemAvailable",
+}
+
+func (s *scrape) memory(area string) (float64, error) {
+	raw, err := s.exec("get_mem", area)
 	if err != nil {
-		return 0.0
+		return 0, err
 	}
 
-	return frequency
+	size, err := vcgencmd.ParseMemory(vcgencmd.FirstValue(raw))
+	return float64(size), err
 }
 
-func temperature() float64 {
-	raw := exec("measure_temp")
-	if raw == nil {
-		return 0.0
+func (s *scrape) clock(kind string) (float64, error) {
+	raw, err := s.exec("measure_clock", kind)
+	if err != nil {
+		return 0, err
 	}
-	value := raw["temp"]
-	temp, err := strconv.ParseFloat(value[:len(value)-2], 64)
+
+	frequency, err := vcgencmd.ParseFrequency(vcgencmd.FirstValue(raw))
+	return float64(frequency), err
+}
+
+func (s *scrape) temperature() (float64, error) {
+	raw, err := s.exec("measure_temp")
 	if err != nil {
-		return 0.0
+		return 0, err
 	}
-	return temp
+
+	temp, err := vcgencmd.ParseTemperature(raw["temp"])
+	return float64(temp), err
 }
 
-func voltage(kind string) float64 {
-	raw := exec("measure_volts", kind)
-	if raw == nil {
-		return 0.0
+func (s *scrape) voltage(kind string) (float64, error) {
+	raw, err := s.exec("measure_volts", kind)
+	if err != nil {
+		return 0, err
 	}
-	value := raw["volt"]
-	volt, err := strconv.ParseFloat(value[:len(value)-1], 64)
+
+	volt, err := vcgencmd.ParseVoltage(raw["volt"])
+	return float64(volt), err
+}
+
+func (s *scrape) throttled() (vcgencmd.Throttled, error) {
+	raw, err := s.exec("get_throttled")
 	if err != nil {
-		return 0.0
+		return 0, err
 	}
-	return volt
+
+	return vcgencmd.ParseThrottled(raw["throttled"])
 }
 
-func exec(args ...string) map[string]string {
-	h := Handle{Cmd: vcgencmd.Cmd{}}
-	out, err := h.Cmd.Run(args...)
+// Every command runs at most once per scrape.
+func (s *scrape) exec(args ...string) (map[string]string, error) {
+	key := strings.Join(args, " ")
+	if result, ok := s.results[key]; ok {
+		return result.out, result.err
+	}
+
+	out, age, err := s.h.run(s.ctx, args...)
+	s.results[key] = result{out, err}
 	if err != nil {
-		return nil
+		return nil, err
 	}
 
-	return out
+	s.age = max(s.age, age)
+	return out, nil
 }
@@ -0,0 +1,72 @@
+/*
+Copyright (c) 2025 Tobias Schäfer. All rights reserved.
+Licensed under the MIT license, see LICENSE in the project root for details.
+*/
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/tschaefer/rpinfo/server/log"
+)
+
+// Stream pushes the latest snapshot of the shared sampler as Server-Sent
+// Events. Clients choose the interval, though not below the sampler one.
+func (h Handle) Stream(w http.ResponseWriter, r *http.Request) {
+	interval := h.Sampler.Interval
+	if value := r.URL.Query().Get("interval"); value != "" {
+		d, err := time.ParseDuration(value)
+		if err != nil || d <= 0 {
+			badRequest(w, r, fmt.Sprintf("invalid interval: %s", value))
+			return
+		}
+		interval = max(interval, d)
+	}
+
+	rc := http.NewResponseController(w)
+	// The stream outlives the server write timeout.
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+		go log.RequestError(r, http.StatusInternalServerError, fmt.Sprintf("Failed to start stream: %v", err))
+		JSONError(w, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	if err := rc.Flush(); err != nil {
+		go log.RequestError(r, http.StatusOK, fmt.Sprintf("Failed to flush stream: %v", err))
+		return
+	}
+	go log.RequestInfo(r, http.StatusOK, "Started stream")
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var sent time.Time
+	for {
+		if snapshot, ok := h.Sampler.Latest(); ok && snapshot.Time.After(sent) {
+			data, _ := json.Marshal(snapshot)
+			_, err := fmt.Fprintf(w, "event: snapshot\nid: %d\ndata: %s\n\n", snapshot.Time.UnixMilli(), data)
+			if err == nil {
+				err = rc.Flush()
+			}
+			if err != nil {
+				go log.RequestWarn(r, http.StatusOK, fmt.Sprintf("Stopped stream: %v", err))
+				return
+			}
+			sent = snapshot.Time
+		}
+
+		select {
+		case <-r.Context().Done():
+			go log.RequestInfo(r, http.StatusOK, "Client closed stream")
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -5,40 +5,39 @@ Licensed under the MIT license, see LICENSE in the project root for details.
 package handler
 
 import (
-	"fmt"
-	"strconv"
 	"strings"
+
+	"github.com/tschaefer/rpinfo/vcgencmd"
 )
 
-// StatusFlag represents a single status flag
-type throttledStatusFlag struct {
-	Bit  uint
-	Desc string
+type throttledDetail struct {
+	Hex     string          `json:"hex"`
+	Value   uint32          `json:"value"`
+	Flags   map[string]bool `json:"flags"`
+	Unknown []uint          `json:"unknown"`
 }
 
-var throttledStatusFlags = []throttledStatusFlag{
-	{0, "Undervoltage detected"},
-	{1, "Arm frequency capped"},
-	{2, "Currently throttled"},
-	{3, "Soft temperature limit active"},
-	{16, "Undervoltage has occurred"},
-	{17, "Arm frequency capping has occurred"},
-	{18, "Throttling has occurred"},
-	{19, "Soft temperature limit has occurred"},
+func newThrottledDetail(hex string, value vcgencmd.Throttled) throttledDetail {
+	return throttledDetail{
+		Hex:     hex,
+		Value:   uint32(value),
+		Flags:   value.Flags(),
+		Unknown: value.Unknown(),
+	}
 }
 
-func parseThrottledHex(hexStr string) ([]string, error) {
-	hexStr = strings.TrimPrefix(hexStr, "0x")
-	val, err := strconv.ParseUint(hexStr, 16, 32)
-	if err != nil {
-		return nil, fmt.Errorf("invalid hex string: %v", err)
+func humanThrottled(value vcgencmd.Throttled) string {
+	var messages []string
+	for _, flag := range vcgencmd.ThrottledFlags {
+		if value.Has(1 << flag.Bit) {
+			messages = append(messages, flag.Desc)
+		}
 	}
 
-	var results []string
-	for _, flag := range throttledStatusFlags {
-		if val&(1<<flag.Bit) != 0 {
-			results = append(results, flag.Desc)
-		}
+	message := strings.Join(messages, ", ")
+	if len(message) == 0 {
+		message = "No throttling"
 	}
-	return results, nil
+
+	return message
 }
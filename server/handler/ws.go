@@ -0,0 +1,204 @@
+/*
+Copyright (c) 2025 Tobias Schäfer. All rights reserved.
+Licensed under the MIT license, see LICENSE in the project root for details.
+*/
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/tschaefer/rpinfo/server/log"
+	"github.com/tschaefer/rpinfo/server/sampler"
+)
+
+const (
+	wsWriteWait  = 10 * time.Second
+	wsPongWait   = 60 * time.Second
+	wsPingPeriod = wsPongWait * 9 / 10
+)
+
+var upgrader = websocket.Upgrader{}
+
+// Limiter caps the number of concurrent subscribers
+type Limiter struct {
+	slots chan struct{}
+}
+
+func NewLimiter(n int) *Limiter {
+	return &Limiter{slots: make(chan struct{}, n)}
+}
+
+func (l *Limiter) Acquire() bool {
+	select {
+	case l.slots <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+func (l *Limiter) Release() {
+	<-l.slots
+}
+
+// Client message, action is either subscribe or unsubscribe
+type wsRequest struct {
+	Action  string   `json:"action"`
+	Metrics []string `json:"metrics"`
+}
+
+// Server message, type is one of subscriptions, readings or error
+type wsResponse struct {
+	Type     string             `json:"type"`
+	Metrics  []string           `json:"metrics,omitempty"`
+	Time     *time.Time         `json:"time,omitempty"`
+	Readings map[string]float64 `json:"readings,omitempty"`
+	Detail   string             `json:"detail,omitempty"`
+}
+
+type subscription struct {
+	mu      sync.Mutex
+	metrics []string
+}
+
+func (s *subscription) update(req wsRequest) error {
+	for _, metric := range req.Metrics {
+		if !slices.Contains(sampler.Metrics(), metric) {
+			return fmt.Errorf("unknown metric: %s", metric)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch req.Action {
+	case "subscribe":
+		for _, metric := range req.Metrics {
+			if !slices.Contains(s.metrics, metric) {
+				s.metrics = append(s.metrics, metric)
+			}
+		}
+	case "unsubscribe":
+		s.metrics = slices.DeleteFunc(s.metrics, func(metric string) bool {
+			return slices.Contains(req.Metrics, metric)
+		})
+	default:
+		return fmt.Errorf("unknown action: %s", req.Action)
+	}
+
+	return nil
+}
+
+func (s *subscription) list() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return slices.Clone(s.metrics)
+}
+
+// WebSocket pushes the subscribed readings of the shared sampler whenever
+// a new snapshot is available.
+func (h Handle) WebSocket(w http.ResponseWriter, r *http.Request) {
+	if !h.Subscribers.Acquire() {
+		go log.RequestWarn(r, http.StatusServiceUnavailable, "too many subscribers")
+		JSONError(w, http.StatusServiceUnavailable, "too many subscribers")
+		return
+	}
+	defer h.Subscribers.Release()
+
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// the upgrader has replied already
+		go log.RequestWarn(r, http.StatusBadRequest, fmt.Sprintf("Failed to upgrade: %v", err))
+		return
+	}
+	defer conn.Close()
+	go log.RequestInfo(r, http.StatusSwitchingProtocols, "Started websocket")
+
+	sub := &subscription{}
+	replies := make(chan wsResponse)
+	stop := make(chan struct{})
+	defer close(stop)
+	done := make(chan struct{})
+	go wsRead(conn, sub, replies, stop, done)
+
+	ticker := time.NewTicker(h.Sampler.Interval)
+	defer ticker.Stop()
+	ping := time.NewTicker(wsPingPeriod)
+	defer ping.Stop()
+
+	var sent time.Time
+	for {
+		select {
+		case <-done:
+			go log.RequestInfo(r, http.StatusSwitchingProtocols, "Client closed websocket")
+			return
+		case reply := <-replies:
+			err = wsWrite(conn, reply)
+		case <-ping.C:
+			_ = conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+			err = conn.WriteMessage(websocket.PingMessage, nil)
+		case <-ticker.C:
+			snapshot, ok := h.Sampler.Latest()
+			metrics := sub.list()
+			if !ok || !snapshot.Time.After(sent) || len(metrics) == 0 {
+				continue
+			}
+
+			readings := make(map[string]float64)
+			for _, metric := range metrics {
+				readings[metric], _ = snapshot.Value(metric)
+			}
+			err = wsWrite(conn, wsResponse{Type: "readings", Time: &snapshot.Time, Readings: readings})
+			sent = snapshot.Time
+		}
+
+		if err != nil {
+			go log.RequestWarn(r, http.StatusSwitchingProtocols, fmt.Sprintf("Stopped websocket: %v", err))
+			return
+		}
+	}
+}
+
+// Read client messages until the connection fails or the writer stops.
+func wsRead(conn *websocket.Conn, sub *subscription, replies chan<- wsResponse, stop <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
+
+	_ = conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	})
+
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		reply := wsResponse{Type: "subscriptions"}
+		var req wsRequest
+		if err := json.Unmarshal(data, &req); err != nil {
+			reply = wsResponse{Type: "error", Detail: "invalid message"}
+		} else if err := sub.update(req); err != nil {
+			reply = wsResponse{Type: "error", Detail: err.Error()}
+		}
+		reply.Metrics = sub.list()
+
+		select {
+		case replies <- reply:
+		case <-stop:
+			return
+		}
+	}
+}
+
+func wsWrite(conn *websocket.Conn, msg wsResponse) error {
+	_ = conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+	return conn.WriteJSON(msg)
+}
@@ -0,0 +1,66 @@
+/*
+Copyright (c) 2025 Tobias Schäfer. All rights reserved.
+Licensed under the MIT license, see LICENSE in the project root for details.
+*/
+package influx
+
+import (
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/tschaefer/rpinfo/server/sampler"
+)
+
+var (
+	measurementEscaper = strings.NewReplacer(`,`, `\,`, ` `, `\ `)
+	keyEscaper         = strings.NewReplacer(`,`, `\,`, `=`, `\=`, ` `, `\ `)
+)
+
+// Format describes the line protocol point of a snapshot.
+type Format struct {
+	Measurement string
+	Tags        map[string]string
+}
+
+// Line encodes the snapshot as a single point in InfluxDB line protocol
+// with a field per reading, e.g. temp, volt.core, clock.arm, throttled and
+// throttled.under_voltage, and a timestamp in nanoseconds.
+func (f Format) Line(s sampler.Snapshot) []byte {
+	var b strings.Builder
+
+	b.WriteString(measurementEscaper.Replace(f.Measurement))
+	for _, key := range slices.Sorted(maps.Keys(f.Tags)) {
+		if f.Tags[key] == "" {
+			continue
+		}
+		b.WriteString("," + keyEscaper.Replace(key) + "=" + keyEscaper.Replace(f.Tags[key]))
+	}
+
+	for i, metric := range sampler.Metrics() {
+		value, _ := s.Value(metric)
+		if i == 0 {
+			b.WriteString(" ")
+		} else {
+			b.WriteString(",")
+		}
+		b.WriteString(keyEscaper.Replace(metric) + "=" + field(metric, value))
+	}
+
+	b.WriteString(" " + strconv.FormatInt(s.Time.UnixNano(), 10) + "\n")
+	return []byte(b.String())
+}
+
+// field formats clocks and the throttled bitfield as integer and the flags
+// as boolean.
+func field(metric string, value float64) string {
+	switch {
+	case strings.HasPrefix(metric, "throttled."):
+		return strconv.FormatBool(value != 0)
+	case metric == "throttled", strings.HasPrefix(metric, "clock."):
+		return strconv.FormatInt(int64(value), 10) + "i"
+	default:
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	}
+}
@@ -0,0 +1,40 @@
+/*
+Copyright (c) 2025 Tobias Schäfer. All rights reserved.
+Licensed under the MIT license, see LICENSE in the project root for details.
+*/
+package influx
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tschaefer/rpinfo/server/sampler"
+	"github.com/tschaefer/rpinfo/vcgencmd"
+)
+
+var epoch = time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func snapshot() sampler.Snapshot {
+	return sampler.Snapshot{
+		Time:        epoch,
+		Temperature: 45.5,
+		Voltages:    map[string]vcgencmd.Voltage{"core": 1.35},
+		Clocks:      map[string]vcgencmd.Frequency{"arm": 600000000},
+		Throttled:   vcgencmd.UnderVoltageOccurred,
+	}
+}
+
+func Test_LineEncodesSnapshot(t *testing.T) {
+	format := Format{Measurement: "rpi info", Tags: map[string]string{"host": "pi", "site": "lab,1", "empty": ""}}
+	line := string(format.Line(snapshot()))
+
+	assert.True(t, strings.HasPrefix(line, `rpi\ info,host=pi,site=lab\,1 temp=45.5,volt.core=1.35,`), line)
+	assert.Contains(t, line, ",clock.arm=600000000i,")
+	assert.Contains(t, line, ",throttled=65536i,")
+	assert.Contains(t, line, ",throttled.under_voltage=false,")
+	assert.Contains(t, line, ",throttled.under_voltage_occurred=true,")
+	assert.True(t, strings.HasSuffix(line, " 1735689600000000000\n"), line)
+	assert.Equal(t, 1, strings.Count(line, "\n"))
+}
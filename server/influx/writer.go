@@ -0,0 +1,168 @@
+/*
+Copyright (c) 2025 Tobias Schäfer. All rights reserved.
+Licensed under the MIT license, see LICENSE in the project root for details.
+*/
+package influx
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/tschaefer/rpinfo/version"
+)
+
+// Writer collects a point at the interval and writes the points in batches
+// to the InfluxDB v2 write API, at the latest once the oldest point waited
+// for a batch. Points of a failed write are kept up to a limit of ten
+// batches and retried with exponential backoff, the remaining points are
+// written when the context is done.
+type Writer struct {
+	URL       string
+	Org       string
+	Bucket    string
+	Token     string
+	Interval  time.Duration
+	BatchSize int
+	Collect   func(ctx context.Context) ([]byte, error)
+	Client    *http.Client
+
+	points  [][]byte
+	oldest  time.Time
+	backoff time.Duration
+	retry   time.Time
+}
+
+const (
+	maxBackoff   = 5 * time.Minute
+	flushTimeout = 5 * time.Second
+)
+
+// rejectedError is a client error of the write, e.g. a bad token
+type rejectedError struct {
+	err error
+}
+
+func (e rejectedError) Error() string {
+	return e.err.Error()
+}
+
+// Run writes until the context is done.
+func (w *Writer) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			w.close(ctx)
+			return
+		case <-ticker.C:
+		}
+
+		w.collect(ctx)
+		if w.due(time.Now()) {
+			w.flush(ctx)
+		}
+	}
+}
+
+// due reports whether a batch is full or the oldest point waited for a
+// batch, unless backing off after a failed write.
+func (w *Writer) due(now time.Time) bool {
+	if len(w.points) == 0 || now.Before(w.retry) {
+		return false
+	}
+
+	return len(w.points) >= w.BatchSize || now.Sub(w.oldest) >= time.Duration(w.BatchSize)*w.Interval
+}
+
+// close writes the remaining points, the context is done already.
+func (w *Writer) close(ctx context.Context) {
+	if len(w.points) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), flushTimeout)
+	defer cancel()
+
+	w.flush(ctx)
+}
+
+func (w *Writer) collect(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, w.Interval)
+	defer cancel()
+
+	point, err := w.Collect(ctx)
+	if err != nil {
+		slog.Warn(fmt.Sprintf("influx warn: %v", err))
+		return
+	}
+
+	if len(w.points) == 0 {
+		w.oldest = time.Now()
+	}
+	w.points = append(w.points, point)
+	if limit := 10 * w.BatchSize; len(w.points) > limit {
+		w.points = w.points[len(w.points)-limit:]
+		slog.Warn("influx warn: buffer full, dropped oldest point")
+	}
+}
+
+// flush keeps the points if the write should be retried, i.e. on network
+// and server errors or rate limiting, and doubles the backoff.
+func (w *Writer) flush(ctx context.Context) {
+	err := w.write(ctx, bytes.Join(w.points, nil))
+	if err == nil {
+		w.points, w.backoff, w.retry = nil, 0, time.Time{}
+		return
+	}
+	if _, ok := err.(rejectedError); ok {
+		slog.Error(fmt.Sprintf("influx error: dropped %d points: %v", len(w.points), err))
+		w.points, w.backoff, w.retry = nil, 0, time.Time{}
+		return
+	}
+
+	w.backoff = min(max(2*w.backoff, w.Interval), maxBackoff)
+	w.retry = time.Now().Add(w.backoff)
+	slog.Warn(fmt.Sprintf("influx warn: %v, %d points buffered, retry in %s", err, len(w.points), w.backoff))
+}
+
+func (w *Writer) write(ctx context.Context, body []byte) error {
+	query := url.Values{"org": {w.Org}, "bucket": {w.Bucket}, "precision": {"ns"}}
+	endpoint := strings.TrimSuffix(w.URL, "/") + "/api/v2/write?" + query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	req.Header.Set("User-Agent", "rpinfo/"+version.Release())
+	if w.Token != "" {
+		req.Header.Set("Authorization", "Token "+w.Token)
+	}
+
+	resp, err := w.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 == 2 {
+		return nil
+	}
+
+	detail, _ := io.ReadAll(io.LimitReader(resp.Body, 256))
+	err = fmt.Errorf("unexpected status %d: %s", resp.StatusCode, bytes.TrimSpace(detail))
+	if resp.StatusCode/100 == 4 && resp.StatusCode != http.StatusTooManyRequests {
+		return rejectedError{err}
+	}
+
+	return err
+}
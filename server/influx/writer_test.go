@@ -0,0 +1,156 @@
+/*
+Copyright (c) 2025 Tobias Schäfer. All rights reserved.
+Licensed under the MIT license, see LICENSE in the project root for details.
+*/
+package influx
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type receiver struct {
+	mu       sync.Mutex
+	status   int
+	requests []*http.Request
+	bodies   []string
+}
+
+func (rc *receiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	body, _ := io.ReadAll(r.Body)
+	rc.requests = append(rc.requests, r)
+	rc.bodies = append(rc.bodies, string(body))
+	w.WriteHeader(rc.status)
+}
+
+func newWriter(url string) *Writer {
+	n := 0
+	return &Writer{
+		URL:       url,
+		Org:       "home",
+		Bucket:    "pi",
+		Token:     "secret",
+		Interval:  time.Second,
+		BatchSize: 2,
+		Collect: func(ctx context.Context) ([]byte, error) {
+			n++
+			return fmt.Appendf(nil, "rpinfo temp=%d %d\n", n, n), nil
+		},
+		Client: &http.Client{Timeout: time.Second},
+	}
+}
+
+func Test_WriterWritesBatch(t *testing.T) {
+	rc := &receiver{status: http.StatusNoContent}
+	server := httptest.NewServer(rc)
+	defer server.Close()
+
+	w := newWriter(server.URL + "/")
+	w.collect(context.Background())
+	w.collect(context.Background())
+	w.flush(context.Background())
+
+	assert.Len(t, rc.requests, 1)
+	assert.Equal(t, "/api/v2/write", rc.requests[0].URL.Path)
+	assert.Equal(t, "home", rc.requests[0].URL.Query().Get("org"))
+	assert.Equal(t, "pi", rc.requests[0].URL.Query().Get("bucket"))
+	assert.Equal(t, "ns", rc.requests[0].URL.Query().Get("precision"))
+	assert.Equal(t, "Token secret", rc.requests[0].Header.Get("Authorization"))
+	assert.Equal(t, "rpinfo temp=1 1\nrpinfo temp=2 2\n", rc.bodies[0])
+	assert.Empty(t, w.points)
+}
+
+func Test_WriterKeepsPointsWhileUnavailable(t *testing.T) {
+	rc := &receiver{status: http.StatusServiceUnavailable}
+	server := httptest.NewServer(rc)
+	defer server.Close()
+
+	w := newWriter(server.URL)
+	for range 25 {
+		w.collect(context.Background())
+	}
+	w.flush(context.Background())
+	assert.Len(t, w.points, 20)
+	assert.Equal(t, "rpinfo temp=6 6\n", string(w.points[0]))
+
+	rc.status = http.StatusNoContent
+	w.flush(context.Background())
+	assert.Empty(t, w.points)
+	assert.Len(t, rc.requests, 2)
+}
+
+func Test_WriterDropsRejectedPoints(t *testing.T) {
+	rc := &receiver{status: http.StatusUnauthorized}
+	server := httptest.NewServer(rc)
+	defer server.Close()
+
+	w := newWriter(server.URL)
+	w.collect(context.Background())
+	w.flush(context.Background())
+	assert.Empty(t, w.points)
+}
+
+func Test_WriterIsDueOnFullBatchOrOldestPoint(t *testing.T) {
+	w := newWriter("")
+	now := time.Now()
+	assert.False(t, w.due(now))
+
+	w.collect(context.Background())
+	assert.False(t, w.due(now))
+	assert.True(t, w.due(w.oldest.Add(2*time.Second)))
+
+	w.collect(context.Background())
+	assert.True(t, w.due(now))
+}
+
+func Test_WriterBacksOffAfterFailedWrite(t *testing.T) {
+	rc := &receiver{status: http.StatusServiceUnavailable}
+	server := httptest.NewServer(rc)
+	defer server.Close()
+
+	w := newWriter(server.URL)
+	w.collect(context.Background())
+	w.collect(context.Background())
+	w.flush(context.Background())
+	assert.Equal(t, time.Second, w.backoff)
+	assert.False(t, w.due(time.Now()))
+	assert.True(t, w.due(w.retry))
+
+	w.flush(context.Background())
+	assert.Equal(t, 2*time.Second, w.backoff)
+
+	rc.status = http.StatusNoContent
+	w.flush(context.Background())
+	assert.Empty(t, w.points)
+	assert.Zero(t, w.backoff)
+	assert.True(t, w.retry.IsZero())
+}
+
+func Test_WriterWritesRemainingPointsWhenDone(t *testing.T) {
+	rc := &receiver{status: http.StatusNoContent}
+	server := httptest.NewServer(rc)
+	defer server.Close()
+
+	w := newWriter(server.URL)
+	w.Interval = 10 * time.Millisecond
+	w.BatchSize = 1000
+
+	ctx, cancel := context.WithTimeout(context.Background(), 55*time.Millisecond)
+	defer cancel()
+	w.Run(ctx)
+
+	assert.Len(t, rc.requests, 1)
+	assert.Contains(t, rc.bodies[0], "rpinfo temp=1 1\n")
+	assert.Empty(t, w.points)
+}
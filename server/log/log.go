@@ -9,6 +9,7 @@ import (
 	"log/slog"
 	"net/http"
 	"os"
+	"time"
 )
 
 func Logger(level, format string) error {
@@ -94,3 +95,18 @@ func RequestWarn(r *http.Request, status int, msg string) {
 func RequestError(r *http.Request, status int, msg string) {
 	Request(r, status, slog.LevelError, msg)
 }
+
+func Throttled(t time.Time, bit uint, flag string, set bool) {
+	args := []any{
+		slog.Time("Time", t),
+		slog.Uint64("Bit", uint64(bit)),
+		slog.String("Flag", flag),
+		slog.Bool("Set", set),
+	}
+
+	if set {
+		slog.Warn("Throttled flag set", args...)
+	} else {
+		slog.Info("Throttled flag cleared", args...)
+	}
+}
@@ -12,6 +12,7 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -115,3 +116,28 @@ func Test_RequestInfoWritesInfoMessage(t *testing.T) {
 	assert.Contains(t, b.String(), `"level":"INFO"`)
 	assert.Contains(t, b.String(), `"msg":"This is a info message"`)
 }
+
+func Test_ThrottledWritesTransition(t *testing.T) {
+	var b strings.Builder
+	w := io.Writer(&b)
+	h := slog.NewJSONHandler(w, &slog.HandlerOptions{Level: slog.LevelInfo})
+	l := slog.New(h)
+	slog.SetDefault(l)
+
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	Throttled(now, 0, "Undervoltage detected", true)
+
+	assert.Contains(t, b.String(), `"level":"WARN"`)
+	assert.Contains(t, b.String(), `"msg":"Throttled flag set"`)
+	assert.Contains(t, b.String(), `"Time":"2025-01-01T00:00:00Z"`)
+	assert.Contains(t, b.String(), `"Bit":0`)
+	assert.Contains(t, b.String(), `"Flag":"Undervoltage detected"`)
+	assert.Contains(t, b.String(), `"Set":true`)
+
+	b.Reset()
+	Throttled(now, 0, "Undervoltage detected", false)
+
+	assert.Contains(t, b.String(), `"level":"INFO"`)
+	assert.Contains(t, b.String(), `"msg":"Throttled flag cleared"`)
+	assert.Contains(t, b.String(), `"Set":false`)
+}
@@ -0,0 +1,110 @@
+/*
+Copyright (c) 2025 Tobias Schäfer. All rights reserved.
+Licensed under the MIT license, see LICENSE in the project root for details.
+*/
+package mqtt
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/tschaefer/rpinfo/server/sampler"
+	"github.com/tschaefer/rpinfo/vcgencmd"
+	"github.com/tschaefer/rpinfo/version"
+)
+
+var invalidID = regexp.MustCompile(`[^a-zA-Z0-9_-]+`)
+
+// Device identifies the board in Home Assistant
+type Device struct {
+	Name   string
+	Model  string
+	Serial string
+}
+
+type deviceInfo struct {
+	Identifiers  []string `json:"identifiers"`
+	Name         string   `json:"name"`
+	Model        string   `json:"model,omitempty"`
+	Manufacturer string   `json:"manufacturer"`
+	SerialNumber string   `json:"serial_number,omitempty"`
+	SwVersion    string   `json:"sw_version"`
+}
+
+// entity is the Home Assistant MQTT discovery config of a reading
+type entity struct {
+	Name              string     `json:"name"`
+	UniqueID          string     `json:"unique_id"`
+	StateTopic        string     `json:"state_topic"`
+	AvailabilityTopic string     `json:"availability_topic"`
+	DeviceClass       string     `json:"device_class,omitempty"`
+	StateClass        string     `json:"state_class,omitempty"`
+	Unit              string     `json:"unit_of_measurement,omitempty"`
+	PayloadOn         string     `json:"payload_on,omitempty"`
+	PayloadOff        string     `json:"payload_off,omitempty"`
+	Device            deviceInfo `json:"device"`
+}
+
+// nodeID identifies the device in topics and unique ids, the serial number
+// if known.
+func (d Device) nodeID() string {
+	id := d.Serial
+	if id == "" {
+		id = d.Name
+	}
+
+	return "rpinfo_" + invalidID.ReplaceAllString(id, "_")
+}
+
+// discovery returns the config topic and entity of a reading. Throttled
+// flags are binary sensors, every other reading is a sensor.
+func (p *Publisher) discovery(metric string) (string, entity) {
+	node := p.Device.nodeID()
+	object := strings.ReplaceAll(metric, ".", "_")
+
+	e := entity{
+		UniqueID:          node + "_" + object,
+		StateTopic:        p.stateTopic(metric),
+		AvailabilityTopic: p.availabilityTopic(),
+		Device: deviceInfo{
+			Identifiers:  []string{node},
+			Name:         p.Device.Name,
+			Model:        p.Device.Model,
+			Manufacturer: "Raspberry Pi Ltd",
+			SerialNumber: p.Device.Serial,
+			SwVersion:    version.Release(),
+		},
+	}
+
+	component := "sensor"
+	kind, name, _ := strings.Cut(metric, ".")
+	switch kind {
+	case "temp":
+		e.Name, e.DeviceClass, e.StateClass, e.Unit = "Temperature", "temperature", "measurement", "°C"
+	case "volt":
+		e.Name, e.DeviceClass, e.StateClass, e.Unit = "Voltage "+name, "voltage", "measurement", "V"
+	case "clock":
+		e.Name, e.DeviceClass, e.StateClass, e.Unit = "Clock "+name, "frequency", "measurement", "Hz"
+	case "throttled":
+		e.Name = "Throttled"
+		for _, flag := range vcgencmd.ThrottledFlags {
+			if flag.Name == name {
+				component = "binary_sensor"
+				e.Name, e.DeviceClass, e.PayloadOn, e.PayloadOff = flag.Desc, "problem", "ON", "OFF"
+			}
+		}
+	}
+
+	return p.Discovery + "/" + component + "/" + node + "/" + object + "/config", e
+}
+
+// entities returns the discovery config of every reading by topic
+func (p *Publisher) entities() map[string]entity {
+	entities := make(map[string]entity)
+	for _, metric := range sampler.Metrics() {
+		topic, e := p.discovery(metric)
+		entities[topic] = e
+	}
+
+	return entities
+}
@@ -0,0 +1,63 @@
+/*
+Copyright (c) 2025 Tobias Schäfer. All rights reserved.
+Licensed under the MIT license, see LICENSE in the project root for details.
+*/
+package mqtt
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tschaefer/rpinfo/server/sampler"
+)
+
+func publisher() *Publisher {
+	return &Publisher{
+		Topic:     "rpinfo/pi",
+		Discovery: "homeassistant",
+		Device:    Device{Name: "pi", Model: "Raspberry Pi 4 Model B Rev 1.4", Serial: "10000000abcdef01"},
+	}
+}
+
+func Test_DiscoveryReturnsSensor(t *testing.T) {
+	topic, e := publisher().discovery("volt.sdram_c")
+
+	assert.Equal(t, "homeassistant/sensor/rpinfo_10000000abcdef01/volt_sdram_c/config", topic)
+	assert.Equal(t, "Voltage sdram_c", e.Name)
+	assert.Equal(t, "rpinfo_10000000abcdef01_volt_sdram_c", e.UniqueID)
+	assert.Equal(t, "rpinfo/pi/volt/sdram_c", e.StateTopic)
+	assert.Equal(t, "rpinfo/pi/availability", e.AvailabilityTopic)
+	assert.Equal(t, "voltage", e.DeviceClass)
+	assert.Equal(t, "measurement", e.StateClass)
+	assert.Equal(t, "V", e.Unit)
+	assert.Equal(t, []string{"rpinfo_10000000abcdef01"}, e.Device.Identifiers)
+	assert.Equal(t, "Raspberry Pi 4 Model B Rev 1.4", e.Device.Model)
+	assert.Equal(t, "10000000abcdef01", e.Device.SerialNumber)
+}
+
+func Test_DiscoveryReturnsBinarySensorForFlags(t *testing.T) {
+	topic, e := publisher().discovery("throttled.under_voltage")
+
+	assert.Equal(t, "homeassistant/binary_sensor/rpinfo_10000000abcdef01/throttled_under_voltage/config", topic)
+	assert.Equal(t, "Undervoltage detected", e.Name)
+	assert.Equal(t, "problem", e.DeviceClass)
+	assert.Equal(t, "ON", e.PayloadOn)
+	assert.Equal(t, "OFF", e.PayloadOff)
+	assert.Empty(t, e.Unit)
+}
+
+func Test_DiscoveryUsesNameWithoutSerial(t *testing.T) {
+	p := publisher()
+	p.Device = Device{Name: "pi.local"}
+
+	topic, _ := p.discovery("temp")
+	assert.Equal(t, "homeassistant/sensor/rpinfo_pi_local/temp/config", topic)
+	assert.Len(t, p.entities(), len(sampler.Metrics()))
+}
+
+func Test_PayloadFormatsReadings(t *testing.T) {
+	assert.Equal(t, "45.5", payload("temp", 45.5))
+	assert.Equal(t, "600000000", payload("clock.arm", 600000000))
+	assert.Equal(t, "ON", payload("throttled.under_voltage", 1))
+	assert.Equal(t, "OFF", payload("throttled.under_voltage", 0))
+}
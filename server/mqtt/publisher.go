@@ -0,0 +1,143 @@
+/*
+Copyright (c) 2025 Tobias Schäfer. All rights reserved.
+Licensed under the MIT license, see LICENSE in the project root for details.
+*/
+package mqtt
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	paho "github.com/eclipse/paho.mqtt.golang"
+	"github.com/tschaefer/rpinfo/server/sampler"
+)
+
+const (
+	online  = "online"
+	offline = "offline"
+)
+
+// Publisher publishes every reading at the interval to a topic below Topic,
+// e.g. rpinfo/pi/volt/core. On connect it announces the readings for Home
+// Assistant MQTT discovery below the Discovery prefix, if set. The broker
+// publishes offline to the availability topic if the connection is lost.
+type Publisher struct {
+	Broker    string
+	ClientID  string
+	Username  string
+	Password  string
+	TLS       *tls.Config
+	Topic     string
+	Discovery string
+	Retain    bool
+	Interval  time.Duration
+	Device    Device
+	Snapshot  func(ctx context.Context) (sampler.Snapshot, error)
+
+	client paho.Client
+}
+
+// Run publishes until the context is done and reports offline on return.
+func (p *Publisher) Run(ctx context.Context) {
+	opts := paho.NewClientOptions().
+		AddBroker(p.Broker).
+		SetClientID(p.ClientID).
+		SetUsername(p.Username).
+		SetPassword(p.Password).
+		SetTLSConfig(p.TLS).
+		SetWill(p.availabilityTopic(), offline, 1, true).
+		SetAutoReconnect(true).
+		SetConnectRetry(true).
+		SetOnConnectHandler(func(paho.Client) { p.announce() }).
+		SetConnectionLostHandler(func(_ paho.Client, err error) {
+			slog.Warn(fmt.Sprintf("mqtt warn: connection lost: %v", err))
+		})
+	p.client = paho.NewClient(opts)
+	p.client.Connect()
+
+	ticker := time.NewTicker(p.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			p.publish(p.availabilityTopic(), offline, true)
+			p.client.Disconnect(250)
+			return
+		case <-ticker.C:
+		}
+
+		if p.client.IsConnectionOpen() {
+			p.readings(ctx)
+		}
+	}
+}
+
+// announce publishes the availability and the discovery configs.
+func (p *Publisher) announce() {
+	p.publish(p.availabilityTopic(), online, true)
+	if p.Discovery == "" {
+		return
+	}
+
+	for topic, e := range p.entities() {
+		payload, err := json.Marshal(e)
+		if err != nil {
+			slog.Error(fmt.Sprintf("mqtt error: %v", err))
+			continue
+		}
+		p.publish(topic, string(payload), true)
+	}
+}
+
+func (p *Publisher) readings(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, p.Interval)
+	defer cancel()
+
+	snapshot, err := p.Snapshot(ctx)
+	if err != nil {
+		slog.Warn(fmt.Sprintf("mqtt warn: %v", err))
+		return
+	}
+
+	for _, metric := range sampler.Metrics() {
+		value, _ := snapshot.Value(metric)
+		p.publish(p.stateTopic(metric), payload(metric, value), p.Retain)
+	}
+}
+
+func (p *Publisher) publish(topic string, payload string, retain bool) {
+	token := p.client.Publish(topic, 1, retain, payload)
+	go func() {
+		if token.WaitTimeout(p.Interval) && token.Error() != nil {
+			slog.Warn(fmt.Sprintf("mqtt warn: publish %s: %v", topic, token.Error()))
+		}
+	}()
+}
+
+func (p *Publisher) availabilityTopic() string {
+	return p.Topic + "/availability"
+}
+
+func (p *Publisher) stateTopic(metric string) string {
+	return p.Topic + "/" + strings.ReplaceAll(metric, ".", "/")
+}
+
+// payload formats throttled flags as ON or OFF and every other reading as
+// number.
+func payload(metric string, value float64) string {
+	if strings.HasPrefix(metric, "throttled.") {
+		if value != 0 {
+			return "ON"
+		}
+		return "OFF"
+	}
+
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
@@ -0,0 +1,123 @@
+/*
+Copyright (c) 2025 Tobias Schäfer. All rights reserved.
+Licensed under the MIT license, see LICENSE in the project root for details.
+*/
+package mqtt
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	broker "github.com/mochi-mqtt/server/v2"
+	"github.com/mochi-mqtt/server/v2/hooks/auth"
+	"github.com/mochi-mqtt/server/v2/listeners"
+	"github.com/mochi-mqtt/server/v2/packets"
+	"github.com/stretchr/testify/assert"
+	"github.com/tschaefer/rpinfo/server/sampler"
+	"github.com/tschaefer/rpinfo/vcgencmd"
+)
+
+type messages struct {
+	mu       sync.Mutex
+	payloads map[string]string
+	retained map[string]bool
+}
+
+func (m *messages) get(topic string) (string, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	payload, ok := m.payloads[topic]
+	return payload, ok
+}
+
+// startBroker runs an embedded broker and records every published message.
+func startBroker(t *testing.T) (string, *messages) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to find free port: %v", err)
+	}
+	address := l.Addr().String()
+	l.Close()
+
+	server := broker.New(&broker.Options{InlineClient: true})
+	_ = server.AddHook(new(auth.AllowHook), nil)
+	if err := server.AddListener(listeners.NewTCP(listeners.Config{ID: "test", Address: address})); err != nil {
+		t.Fatalf("failed to add listener: %v", err)
+	}
+	if err := server.Serve(); err != nil {
+		t.Fatalf("failed to start broker: %v", err)
+	}
+	t.Cleanup(func() { server.Close() })
+
+	m := &messages{payloads: make(map[string]string), retained: make(map[string]bool)}
+	_ = server.Subscribe("#", 1, func(_ *broker.Client, _ packets.Subscription, pk packets.Packet) {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		m.payloads[pk.TopicName] = string(pk.Payload)
+		m.retained[pk.TopicName] = pk.FixedHeader.Retain
+	})
+
+	return "tcp://" + address, m
+}
+
+func Test_PublisherPublishesReadingsAndDiscovery(t *testing.T) {
+	url, m := startBroker(t)
+
+	p := publisher()
+	p.Broker = url
+	p.ClientID = "rpinfo-test"
+	p.Interval = 20 * time.Millisecond
+	p.Snapshot = func(ctx context.Context) (sampler.Snapshot, error) {
+		return sampler.Snapshot{
+			Temperature: 45.5,
+			Voltages:    map[string]vcgencmd.Voltage{"core": 1.35},
+			Clocks:      map[string]vcgencmd.Frequency{"arm": 600000000},
+			Throttled:   vcgencmd.UnderVoltage,
+		}, nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		p.Run(ctx)
+		close(done)
+	}()
+
+	assert.Eventually(t, func() bool {
+		_, ok := m.get("rpinfo/pi/throttled/under_voltage")
+		return ok
+	}, 5*time.Second, 10*time.Millisecond)
+
+	availability, _ := m.get("rpinfo/pi/availability")
+	assert.Equal(t, "online", availability)
+	temp, _ := m.get("rpinfo/pi/temp")
+	assert.Equal(t, "45.5", temp)
+	clock, _ := m.get("rpinfo/pi/clock/arm")
+	assert.Equal(t, "600000000", clock)
+	flag, _ := m.get("rpinfo/pi/throttled/under_voltage")
+	assert.Equal(t, "ON", flag)
+
+	config, ok := m.get("homeassistant/sensor/rpinfo_10000000abcdef01/temp/config")
+	assert.True(t, ok)
+	var e entity
+	assert.Nil(t, json.Unmarshal([]byte(config), &e))
+	assert.Equal(t, "temperature", e.DeviceClass)
+	assert.Equal(t, "rpinfo/pi/temp", e.StateTopic)
+
+	m.mu.Lock()
+	assert.True(t, m.retained["homeassistant/sensor/rpinfo_10000000abcdef01/temp/config"])
+	assert.False(t, m.retained["rpinfo/pi/temp"])
+	m.mu.Unlock()
+
+	cancel()
+	<-done
+	assert.Eventually(t, func() bool {
+		availability, _ := m.get("rpinfo/pi/availability")
+		return availability == "offline"
+	}, 5*time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,175 @@
+/*
+Copyright (c) 2025 Tobias Schäfer. All rights reserved.
+Licensed under the MIT license, see LICENSE in the project root for details.
+*/
+package otlp
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	"go.opentelemetry.io/otel/metric"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
+	"google.golang.org/grpc/credentials"
+
+	"github.com/tschaefer/rpinfo/server/sampler"
+	"github.com/tschaefer/rpinfo/server/watcher"
+	"github.com/tschaefer/rpinfo/vcgencmd"
+	"github.com/tschaefer/rpinfo/version"
+)
+
+// Exporter exports the readings of the metrics endpoint at the interval to
+// an OpenTelemetry collector via OTLP over HTTP or gRPC.
+type Exporter struct {
+	Endpoint string
+	Protocol string
+	Headers  map[string]string
+	TLS      *tls.Config
+	Interval time.Duration
+	Host     string
+	Model    string
+	Serial   string
+	Snapshot func(ctx context.Context) (sampler.Snapshot, error)
+	Events   *watcher.Events
+}
+
+// Run exports until the context is done and flushes on return.
+func (e *Exporter) Run(ctx context.Context) {
+	provider, err := e.provider(ctx)
+	if err != nil {
+		slog.Error(fmt.Sprintf("otlp error: %v", err))
+		return
+	}
+
+	<-ctx.Done()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := provider.Shutdown(ctx); err != nil {
+		slog.Warn(fmt.Sprintf("otlp warn: %v", err))
+	}
+}
+
+// provider returns a meter provider with the readings registered as
+// observable instruments, collected by a periodic reader.
+func (e *Exporter) provider(ctx context.Context) (*sdkmetric.MeterProvider, error) {
+	exporter, err := e.exporter(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	provider := sdkmetric.NewMeterProvider(
+		sdkmetric.WithResource(e.resource()),
+		sdkmetric.WithReader(sdkmetric.NewPeriodicReader(exporter, sdkmetric.WithInterval(e.Interval))),
+	)
+	if err := e.register(provider.Meter("github.com/tschaefer/rpinfo")); err != nil {
+		_ = provider.Shutdown(ctx)
+		return nil, err
+	}
+
+	return provider, nil
+}
+
+func (e *Exporter) exporter(ctx context.Context) (sdkmetric.Exporter, error) {
+	switch e.Protocol {
+	case "http", "":
+		opts := []otlpmetrichttp.Option{otlpmetrichttp.WithEndpointURL(e.Endpoint), otlpmetrichttp.WithHeaders(e.Headers)}
+		if e.TLS != nil {
+			opts = append(opts, otlpmetrichttp.WithTLSClientConfig(e.TLS))
+		}
+		return otlpmetrichttp.New(ctx, opts...)
+	case "grpc":
+		opts := []otlpmetricgrpc.Option{otlpmetricgrpc.WithEndpointURL(e.Endpoint), otlpmetricgrpc.WithHeaders(e.Headers)}
+		if e.TLS != nil {
+			opts = append(opts, otlpmetricgrpc.WithTLSCredentials(credentials.NewTLS(e.TLS)))
+		}
+		return otlpmetricgrpc.New(ctx, opts...)
+	default:
+		return nil, fmt.Errorf("unknown protocol: %s", e.Protocol)
+	}
+}
+
+func (e *Exporter) resource() *resource.Resource {
+	return resource.NewSchemaless(
+		attribute.String("service.name", "rpinfo"),
+		attribute.String("service.version", version.Release()),
+		attribute.String("host.name", e.Host),
+		attribute.String("rpi.board.model", e.Model),
+		attribute.String("rpi.board.serial", e.Serial),
+	)
+}
+
+// register creates the instruments of the metrics endpoint. A single
+// snapshot is taken per collection and observed by all instruments.
+func (e *Exporter) register(meter metric.Meter) error {
+	clock, err := meter.Float64ObservableGauge("rpi.clock.frequency", metric.WithUnit("Hz"), metric.WithDescription("Clock frequency."))
+	if err != nil {
+		return err
+	}
+	temperature, err := meter.Float64ObservableGauge("rpi.temperature", metric.WithUnit("Cel"), metric.WithDescription("SoC temperature."))
+	if err != nil {
+		return err
+	}
+	voltage, err := meter.Float64ObservableGauge("rpi.voltage", metric.WithUnit("V"), metric.WithDescription("Rail voltage."))
+	if err != nil {
+		return err
+	}
+	throttled, err := meter.Int64ObservableGauge("rpi.throttled", metric.WithDescription("Throttled state bitfield."))
+	if err != nil {
+		return err
+	}
+	flag, err := meter.Int64ObservableGauge("rpi.throttled.flag", metric.WithDescription("Throttled state flag, 1 if set."))
+	if err != nil {
+		return err
+	}
+
+	instruments := []metric.Observable{clock, temperature, voltage, throttled, flag}
+	var transitions metric.Int64ObservableCounter
+	if e.Events != nil {
+		transitions, err = meter.Int64ObservableCounter("rpi.throttled.transitions", metric.WithDescription("Observed throttled flag transitions."))
+		if err != nil {
+			return err
+		}
+		instruments = append(instruments, transitions)
+	}
+
+	_, err = meter.RegisterCallback(func(ctx context.Context, o metric.Observer) error {
+		if e.Events != nil {
+			for _, f := range vcgencmd.ThrottledFlags {
+				for _, state := range []string{"set", "cleared"} {
+					count := e.Events.Count(f.Bit, state == "set")
+					o.ObserveInt64(transitions, int64(count), metric.WithAttributes(attribute.String("flag", f.Name), attribute.String("state", state)))
+				}
+			}
+		}
+
+		snapshot, err := e.Snapshot(ctx)
+		if err != nil {
+			slog.Warn(fmt.Sprintf("otlp warn: %v", err))
+			return nil
+		}
+
+		for name, value := range snapshot.Clocks {
+			o.ObserveFloat64(clock, float64(value), metric.WithAttributes(attribute.String("clock", name)))
+		}
+		o.ObserveFloat64(temperature, float64(snapshot.Temperature))
+		for name, value := range snapshot.Voltages {
+			o.ObserveFloat64(voltage, float64(value), metric.WithAttributes(attribute.String("rail", name)))
+		}
+		o.ObserveInt64(throttled, int64(snapshot.Throttled))
+		for _, f := range vcgencmd.ThrottledFlags {
+			o.ObserveInt64(flag, int64(snapshot.Throttled>>f.Bit&1), metric.WithAttributes(attribute.String("flag", f.Name)))
+		}
+
+		return nil
+	}, instruments...)
+
+	return err
+}
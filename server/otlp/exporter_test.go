@@ -0,0 +1,149 @@
+/*
+Copyright (c) 2025 Tobias Schäfer. All rights reserved.
+Licensed under the MIT license, see LICENSE in the project root for details.
+*/
+package otlp
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	collector "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/tschaefer/rpinfo/server/sampler"
+	"github.com/tschaefer/rpinfo/server/watcher"
+	"github.com/tschaefer/rpinfo/vcgencmd"
+)
+
+type receiver struct {
+	mu       sync.Mutex
+	headers  []http.Header
+	requests []*collector.ExportMetricsServiceRequest
+}
+
+func (rc *receiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	body, _ := io.ReadAll(r.Body)
+	request := &collector.ExportMetricsServiceRequest{}
+	if err := proto.Unmarshal(body, request); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	rc.headers = append(rc.headers, r.Header)
+	rc.requests = append(rc.requests, request)
+
+	w.Header().Set("Content-Type", "application/x-protobuf")
+	out, _ := proto.Marshal(&collector.ExportMetricsServiceResponse{})
+	_, _ = w.Write(out)
+}
+
+func newExporter(url string) *Exporter {
+	return &Exporter{
+		Endpoint: url + "/v1/metrics",
+		Protocol: "http",
+		Headers:  map[string]string{"X-Scope-OrgID": "pi"},
+		Interval: time.Hour,
+		Host:     "pi",
+		Model:    "Raspberry Pi 4 Model B Rev 1.4",
+		Serial:   "10000000abcdef01",
+		Snapshot: func(ctx context.Context) (sampler.Snapshot, error) {
+			return sampler.Snapshot{
+				Time:        time.Now(),
+				Temperature: 42.8,
+				Voltages:    map[string]vcgencmd.Voltage{"core": 0.85},
+				Clocks:      map[string]vcgencmd.Frequency{"arm": 1500000000},
+				Throttled:   0x50005,
+			}, nil
+		},
+	}
+}
+
+func metrics(request *collector.ExportMetricsServiceRequest) map[string]*metricspb.Metric {
+	all := map[string]*metricspb.Metric{}
+	for _, rm := range request.ResourceMetrics {
+		for _, sm := range rm.ScopeMetrics {
+			for _, m := range sm.Metrics {
+				all[m.Name] = m
+			}
+		}
+	}
+
+	return all
+}
+
+func attributes(request *collector.ExportMetricsServiceRequest) map[string]string {
+	all := map[string]string{}
+	for _, kv := range request.ResourceMetrics[0].Resource.Attributes {
+		all[kv.Key] = kv.Value.GetStringValue()
+	}
+
+	return all
+}
+
+func Test_ExporterExportsReadings(t *testing.T) {
+	rc := &receiver{}
+	server := httptest.NewServer(rc)
+	defer server.Close()
+
+	e := newExporter(server.URL)
+	provider, err := e.provider(context.Background())
+	assert.NoError(t, err)
+	assert.NoError(t, provider.ForceFlush(context.Background()))
+	assert.NoError(t, provider.Shutdown(context.Background()))
+
+	assert.NotEmpty(t, rc.requests)
+	assert.Equal(t, "pi", rc.headers[0].Get("X-Scope-OrgID"))
+
+	resource := attributes(rc.requests[0])
+	assert.Equal(t, "rpinfo", resource["service.name"])
+	assert.Equal(t, "dev", resource["service.version"])
+	assert.Equal(t, "pi", resource["host.name"])
+	assert.Equal(t, "Raspberry Pi 4 Model B Rev 1.4", resource["rpi.board.model"])
+	assert.Equal(t, "10000000abcdef01", resource["rpi.board.serial"])
+
+	all := metrics(rc.requests[0])
+	assert.Equal(t, 42.8, all["rpi.temperature"].GetGauge().DataPoints[0].GetAsDouble())
+	assert.Equal(t, "Cel", all["rpi.temperature"].Unit)
+	assert.Equal(t, 0.85, all["rpi.voltage"].GetGauge().DataPoints[0].GetAsDouble())
+	assert.Equal(t, "rail", all["rpi.voltage"].GetGauge().DataPoints[0].Attributes[0].Key)
+	assert.Equal(t, 1500000000.0, all["rpi.clock.frequency"].GetGauge().DataPoints[0].GetAsDouble())
+	assert.Equal(t, int64(0x50005), all["rpi.throttled"].GetGauge().DataPoints[0].GetAsInt())
+	assert.Len(t, all["rpi.throttled.flag"].GetGauge().DataPoints, len(vcgencmd.ThrottledFlags))
+	assert.NotContains(t, all, "rpi.throttled.transitions")
+}
+
+func Test_ExporterExportsTransitions(t *testing.T) {
+	rc := &receiver{}
+	server := httptest.NewServer(rc)
+	defer server.Close()
+
+	e := newExporter(server.URL)
+	e.Events = watcher.NewEvents(10)
+	provider, err := e.provider(context.Background())
+	assert.NoError(t, err)
+	assert.NoError(t, provider.ForceFlush(context.Background()))
+	assert.NoError(t, provider.Shutdown(context.Background()))
+
+	all := metrics(rc.requests[0])
+	transitions := all["rpi.throttled.transitions"].GetSum()
+	assert.True(t, transitions.IsMonotonic)
+	assert.Len(t, transitions.DataPoints, 2*len(vcgencmd.ThrottledFlags))
+}
+
+func Test_ExporterRejectsUnknownProtocol(t *testing.T) {
+	e := newExporter("http://localhost")
+	e.Protocol = "udp"
+
+	_, err := e.provider(context.Background())
+	assert.EqualError(t, err, "unknown protocol: udp")
+}
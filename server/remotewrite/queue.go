@@ -0,0 +1,62 @@
+/*
+Copyright (c) 2025 Tobias Schäfer. All rights reserved.
+Licensed under the MIT license, see LICENSE in the project root for details.
+*/
+package remotewrite
+
+import "sync"
+
+// Queue keeps batches of series not yet sent up to a fixed size, the
+// oldest batch is dropped once full.
+type Queue struct {
+	mu      sync.Mutex
+	size    int
+	batches [][]TimeSeries
+}
+
+func NewQueue(size int) *Queue {
+	return &Queue{size: size}
+}
+
+// Push appends a batch and reports whether the oldest batch was dropped.
+func (q *Queue) Push(batch []TimeSeries) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.batches = append(q.batches, batch)
+	if len(q.batches) > q.size {
+		q.batches = q.batches[1:]
+		return true
+	}
+
+	return false
+}
+
+// Peek returns the oldest batch without removing it.
+func (q *Queue) Peek() ([]TimeSeries, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if len(q.batches) == 0 {
+		return nil, false
+	}
+
+	return q.batches[0], true
+}
+
+// Pop removes the oldest batch.
+func (q *Queue) Pop() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if len(q.batches) > 0 {
+		q.batches = q.batches[1:]
+	}
+}
+
+func (q *Queue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return len(q.batches)
+}
@@ -0,0 +1,39 @@
+/*
+Copyright (c) 2025 Tobias Schäfer. All rights reserved.
+Licensed under the MIT license, see LICENSE in the project root for details.
+*/
+package remotewrite
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func batch(value float64) []TimeSeries {
+	return []TimeSeries{{Samples: []Sample{{Value: value}}}}
+}
+
+func Test_QueueDropsOldestBatch(t *testing.T) {
+	q := NewQueue(2)
+
+	_, ok := q.Peek()
+	assert.False(t, ok)
+
+	assert.False(t, q.Push(batch(1)))
+	assert.False(t, q.Push(batch(2)))
+	assert.True(t, q.Push(batch(3)))
+	assert.Equal(t, 2, q.Len())
+
+	oldest, ok := q.Peek()
+	assert.True(t, ok)
+	assert.Equal(t, batch(2), oldest)
+
+	q.Pop()
+	oldest, _ = q.Peek()
+	assert.Equal(t, batch(3), oldest)
+
+	q.Pop()
+	q.Pop()
+	assert.Equal(t, 0, q.Len())
+}
@@ -0,0 +1,151 @@
+/*
+Copyright (c) 2025 Tobias Schäfer. All rights reserved.
+Licensed under the MIT license, see LICENSE in the project root for details.
+*/
+package remotewrite
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+type Label struct {
+	Name  string
+	Value string
+}
+
+// Sample is a value at a timestamp in milliseconds
+type Sample struct {
+	Value     float64
+	Timestamp int64
+}
+
+type TimeSeries struct {
+	Labels  []Label
+	Samples []Sample
+}
+
+// Parse converts the Prometheus text format into time series sampled at t.
+// The labels of every series are sorted by name and extended by the given
+// labels unless already set.
+func Parse(text []byte, t time.Time, labels map[string]string) ([]TimeSeries, error) {
+	var series []TimeSeries
+
+	scanner := bufio.NewScanner(bytes.NewReader(text))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		i := strings.LastIndexByte(line, ' ')
+		if i < 0 {
+			return nil, fmt.Errorf("invalid sample: %q", line)
+		}
+		value, err := strconv.ParseFloat(line[i+1:], 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid sample: %q", line)
+		}
+
+		set, err := parseMetric(line[:i])
+		if err != nil {
+			return nil, err
+		}
+		for name, value := range labels {
+			if _, ok := set[name]; !ok {
+				set[name] = value
+			}
+		}
+
+		ts := TimeSeries{Samples: []Sample{{Value: value, Timestamp: t.UnixMilli()}}}
+		for _, name := range slices.Sorted(maps.Keys(set)) {
+			ts.Labels = append(ts.Labels, Label{Name: name, Value: set[name]})
+		}
+		series = append(series, ts)
+	}
+
+	return series, scanner.Err()
+}
+
+// parseMetric parses a metric like name{key="value"} into its labels
+func parseMetric(metric string) (map[string]string, error) {
+	name, rest, _ := strings.Cut(metric, "{")
+	set := map[string]string{"__name__": name}
+	if rest == "" {
+		return set, nil
+	}
+
+	rest, ok := strings.CutSuffix(rest, "}")
+	if !ok {
+		return nil, fmt.Errorf("invalid metric: %q", metric)
+	}
+	for rest != "" {
+		key, value, ok := strings.Cut(rest, "=")
+		if !ok || !strings.HasPrefix(value, `"`) {
+			return nil, fmt.Errorf("invalid metric: %q", metric)
+		}
+
+		end := 1
+		for end < len(value) && value[end] != '"' {
+			if value[end] == '\\' {
+				end++
+			}
+			end++
+		}
+		if end >= len(value) {
+			return nil, fmt.Errorf("invalid metric: %q", metric)
+		}
+
+		unquoted, err := strconv.Unquote(value[:end+1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid metric: %q", metric)
+		}
+		set[key] = unquoted
+		rest = strings.TrimPrefix(value[end+1:], ",")
+	}
+
+	return set, nil
+}
+
+// Marshal encodes the series as protobuf WriteRequest of the remote write
+// protocol.
+func Marshal(series []TimeSeries) []byte {
+	var request []byte
+	for _, ts := range series {
+		var message []byte
+		for _, label := range ts.Labels {
+			var l []byte
+			l = protowire.AppendTag(l, 1, protowire.BytesType)
+			l = protowire.AppendString(l, label.Name)
+			l = protowire.AppendTag(l, 2, protowire.BytesType)
+			l = protowire.AppendString(l, label.Value)
+
+			message = protowire.AppendTag(message, 1, protowire.BytesType)
+			message = protowire.AppendBytes(message, l)
+		}
+		for _, sample := range ts.Samples {
+			var s []byte
+			s = protowire.AppendTag(s, 1, protowire.Fixed64Type)
+			s = protowire.AppendFixed64(s, math.Float64bits(sample.Value))
+			s = protowire.AppendTag(s, 2, protowire.VarintType)
+			s = protowire.AppendVarint(s, uint64(sample.Timestamp))
+
+			message = protowire.AppendTag(message, 2, protowire.BytesType)
+			message = protowire.AppendBytes(message, s)
+		}
+
+		request = protowire.AppendTag(request, 1, protowire.BytesType)
+		request = protowire.AppendBytes(request, message)
+	}
+
+	return request
+}
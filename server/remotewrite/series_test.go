@@ -0,0 +1,98 @@
+/*
+Copyright (c) 2025 Tobias Schäfer. All rights reserved.
+Licensed under the MIT license, see LICENSE in the project root for details.
+*/
+package remotewrite
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+var epoch = time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func Test_ParseReturnsSortedSeries(t *testing.T) {
+	text := "# HELP rpi_clock_hertz Clock frequency in hertz.\n" +
+		"# TYPE rpi_clock_hertz gauge\n" +
+		"rpi_clock_hertz{clock=\"arm\"} 600000000\n" +
+		"rpi_temperature_celsius 45.5\n" +
+		"rpi_info{version=\"v1\",model=\"Pi \\\"4\\\"\"} 1\n"
+
+	series, err := Parse([]byte(text), epoch, map[string]string{"instance": "pi", "clock": "ignored"})
+	assert.Nil(t, err)
+	assert.Equal(t, []TimeSeries{
+		{
+			Labels: []Label{
+				{"__name__", "rpi_clock_hertz"}, {"clock", "arm"}, {"instance", "pi"},
+			},
+			Samples: []Sample{{600000000, epoch.UnixMilli()}},
+		},
+		{
+			Labels: []Label{
+				{"__name__", "rpi_temperature_celsius"}, {"clock", "ignored"}, {"instance", "pi"},
+			},
+			Samples: []Sample{{45.5, epoch.UnixMilli()}},
+		},
+		{
+			Labels: []Label{
+				{"__name__", "rpi_info"}, {"clock", "ignored"}, {"instance", "pi"},
+				{"model", `Pi "4"`}, {"version", "v1"},
+			},
+			Samples: []Sample{{1, epoch.UnixMilli()}},
+		},
+	}, series)
+}
+
+func Test_ParseReturnsErrorIfSampleIsInvalid(t *testing.T) {
+	for _, text := range []string{
+		"rpi_temperature_celsius\n",
+		"rpi_temperature_celsius hot\n",
+		"rpi_clock_hertz{clock=\"arm\" 1\n",
+		"rpi_clock_hertz{clock=arm} 1\n",
+		"rpi_clock_hertz{clock=\"arm} 1\n",
+	} {
+		_, err := Parse([]byte(text), epoch, nil)
+		assert.NotNil(t, err, text)
+	}
+}
+
+func Test_MarshalEncodesWriteRequest(t *testing.T) {
+	series := []TimeSeries{{
+		Labels:  []Label{{"__name__", "rpi_temperature_celsius"}},
+		Samples: []Sample{{45.5, 1700000000000}},
+	}}
+
+	request := Marshal(series)
+
+	num, typ, n := protowire.ConsumeTag(request)
+	assert.Equal(t, protowire.Number(1), num)
+	assert.Equal(t, protowire.BytesType, typ)
+	message, _ := protowire.ConsumeBytes(request[n:])
+
+	num, _, n = protowire.ConsumeTag(message)
+	assert.Equal(t, protowire.Number(1), num)
+	label, m := protowire.ConsumeBytes(message[n:])
+	message = message[n+m:]
+
+	_, _, n = protowire.ConsumeTag(label)
+	name, m := protowire.ConsumeString(label[n:])
+	assert.Equal(t, "__name__", name)
+	_, _, n = protowire.ConsumeTag(label[n+m:])
+	value, _ := protowire.ConsumeString(label[n+m+n:])
+	assert.Equal(t, "rpi_temperature_celsius", value)
+
+	num, _, n = protowire.ConsumeTag(message)
+	assert.Equal(t, protowire.Number(2), num)
+	sample, _ := protowire.ConsumeBytes(message[n:])
+
+	_, _, n = protowire.ConsumeTag(sample)
+	bits, m := protowire.ConsumeFixed64(sample[n:])
+	assert.Equal(t, 45.5, math.Float64frombits(bits))
+	_, _, n2 := protowire.ConsumeTag(sample[n+m:])
+	timestamp, _ := protowire.ConsumeVarint(sample[n+m+n2:])
+	assert.Equal(t, uint64(1700000000000), timestamp)
+}
@@ -0,0 +1,137 @@
+/*
+Copyright (c) 2025 Tobias Schäfer. All rights reserved.
+Licensed under the MIT license, see LICENSE in the project root for details.
+*/
+package remotewrite
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/tschaefer/rpinfo/version"
+)
+
+// Writer periodically gathers the metrics and pushes them with the
+// Prometheus remote write protocol. Batches the receiver did not accept are
+// kept in the queue and retried on the next push.
+type Writer struct {
+	URL      string
+	Token    string
+	Username string
+	Password string
+	Interval time.Duration
+	// Added to every series unless already set, e.g. instance
+	Labels map[string]string
+	Gather func(ctx context.Context) []byte
+	Queue  *Queue
+	Client *http.Client
+}
+
+// permanentError is a rejection which is not worth a retry
+type permanentError struct {
+	err error
+}
+
+func (e permanentError) Error() string {
+	return e.err.Error()
+}
+
+// Run pushes at the interval until the context is done.
+func (w *Writer) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		w.collect(ctx)
+		w.flush(ctx)
+	}
+}
+
+func (w *Writer) collect(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, w.Interval)
+	defer cancel()
+
+	series, err := Parse(w.Gather(ctx), time.Now(), w.Labels)
+	if err != nil {
+		slog.Warn(fmt.Sprintf("remote write warn: %v", err))
+		return
+	}
+
+	if w.Queue.Push(series) {
+		slog.Warn("remote write warn: queue full, dropped oldest batch")
+	}
+}
+
+// flush sends the queued batches oldest first and stops at the first
+// batch that should be retried.
+func (w *Writer) flush(ctx context.Context) {
+	for {
+		batch, ok := w.Queue.Peek()
+		if !ok {
+			return
+		}
+
+		err := w.send(ctx, batch)
+		if err == nil {
+			w.Queue.Pop()
+			continue
+		}
+		if _, ok := err.(permanentError); ok {
+			slog.Error(fmt.Sprintf("remote write error: dropped batch: %v", err))
+			w.Queue.Pop()
+			continue
+		}
+
+		slog.Warn(fmt.Sprintf("remote write warn: %v, %d batches queued", err, w.Queue.Len()))
+		return
+	}
+}
+
+func (w *Writer) send(ctx context.Context, batch []TimeSeries) error {
+	body := snappy.Encode(nil, Marshal(batch))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return permanentError{err}
+	}
+	req.Header.Set("Content-Encoding", "snappy")
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("User-Agent", "rpinfo/"+version.Release())
+	req.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+	if w.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+w.Token)
+	} else if w.Username != "" {
+		req.SetBasicAuth(w.Username, w.Password)
+	}
+
+	resp, err := w.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 == 2 {
+		return nil
+	}
+
+	detail, _ := io.ReadAll(io.LimitReader(resp.Body, 256))
+	err = fmt.Errorf("unexpected status %d: %s", resp.StatusCode, bytes.TrimSpace(detail))
+	// Only server errors and rate limiting are retried by the protocol.
+	if resp.StatusCode/100 == 5 || resp.StatusCode == http.StatusTooManyRequests {
+		return err
+	}
+
+	return permanentError{err}
+}
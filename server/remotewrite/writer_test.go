@@ -0,0 +1,133 @@
+/*
+Copyright (c) 2025 Tobias Schäfer. All rights reserved.
+Licensed under the MIT license, see LICENSE in the project root for details.
+*/
+package remotewrite
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/stretchr/testify/assert"
+)
+
+type receiver struct {
+	mu       sync.Mutex
+	status   int
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func (rc *receiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	body, _ := io.ReadAll(r.Body)
+	decoded, _ := snappy.Decode(nil, body)
+	rc.requests = append(rc.requests, r)
+	rc.bodies = append(rc.bodies, decoded)
+	w.WriteHeader(rc.status)
+}
+
+func newWriter(url string) *Writer {
+	return &Writer{
+		URL:      url,
+		Token:    "secret",
+		Interval: time.Second,
+		Labels:   map[string]string{"instance": "pi"},
+		Gather: func(ctx context.Context) []byte {
+			return []byte("rpi_temperature_celsius 45.5\n")
+		},
+		Queue:  NewQueue(10),
+		Client: &http.Client{Timeout: time.Second},
+	}
+}
+
+func Test_WriterPushesSnappyProtobuf(t *testing.T) {
+	rc := &receiver{status: http.StatusNoContent}
+	server := httptest.NewServer(rc)
+	defer server.Close()
+
+	w := newWriter(server.URL)
+	w.collect(context.Background())
+	w.flush(context.Background())
+
+	assert.Equal(t, 0, w.Queue.Len())
+	assert.Len(t, rc.requests, 1)
+	assert.Equal(t, "snappy", rc.requests[0].Header.Get("Content-Encoding"))
+	assert.Equal(t, "application/x-protobuf", rc.requests[0].Header.Get("Content-Type"))
+	assert.Equal(t, "0.1.0", rc.requests[0].Header.Get("X-Prometheus-Remote-Write-Version"))
+	assert.Equal(t, "Bearer secret", rc.requests[0].Header.Get("Authorization"))
+
+	assert.Contains(t, string(rc.bodies[0]), "rpi_temperature_celsius")
+	assert.Contains(t, string(rc.bodies[0]), "instance")
+}
+
+func Test_WriterUsesBasicAuth(t *testing.T) {
+	rc := &receiver{status: http.StatusOK}
+	server := httptest.NewServer(rc)
+	defer server.Close()
+
+	w := newWriter(server.URL)
+	w.Token, w.Username, w.Password = "", "user", "pass"
+	w.collect(context.Background())
+	w.flush(context.Background())
+
+	username, password, ok := rc.requests[0].BasicAuth()
+	assert.True(t, ok)
+	assert.Equal(t, "user", username)
+	assert.Equal(t, "pass", password)
+}
+
+func Test_WriterQueuesWhileReceiverIsUnavailable(t *testing.T) {
+	rc := &receiver{status: http.StatusServiceUnavailable}
+	server := httptest.NewServer(rc)
+	defer server.Close()
+
+	w := newWriter(server.URL)
+	for range 3 {
+		w.collect(context.Background())
+		w.flush(context.Background())
+	}
+	assert.Equal(t, 3, w.Queue.Len())
+	assert.Len(t, rc.requests, 3)
+
+	rc.mu.Lock()
+	rc.status = http.StatusOK
+	rc.mu.Unlock()
+
+	w.collect(context.Background())
+	w.flush(context.Background())
+	assert.Equal(t, 0, w.Queue.Len())
+	assert.Len(t, rc.requests, 7)
+}
+
+func Test_WriterDropsRejectedBatch(t *testing.T) {
+	rc := &receiver{status: http.StatusBadRequest}
+	server := httptest.NewServer(rc)
+	defer server.Close()
+
+	w := newWriter(server.URL)
+	w.collect(context.Background())
+	w.flush(context.Background())
+
+	assert.Equal(t, 0, w.Queue.Len())
+	assert.Len(t, rc.requests, 1)
+}
+
+func Test_WriterQueuesIfReceiverIsUnreachable(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+
+	w := newWriter(server.URL)
+	w.collect(context.Background())
+	w.flush(context.Background())
+
+	assert.Equal(t, 1, w.Queue.Len())
+}
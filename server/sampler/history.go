@@ -0,0 +1,97 @@
+/*
+Copyright (c) 2025 Tobias Schäfer. All rights reserved.
+Licensed under the MIT license, see LICENSE in the project root for details.
+*/
+package sampler
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// History is a fixed size ring buffer of snapshots, the oldest snapshot is
+// overwritten once it is full.
+type History struct {
+	mu        sync.RWMutex
+	snapshots []Snapshot
+	next      int
+	full      bool
+}
+
+func NewHistory(size int) *History {
+	return &History{snapshots: make([]Snapshot, size)}
+}
+
+func (h *History) Add(snapshot Snapshot) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.snapshots[h.next] = snapshot
+	h.next = (h.next + 1) % len(h.snapshots)
+	if h.next == 0 {
+		h.full = true
+	}
+}
+
+// Range returns the snapshots within [since, until] in chronological order.
+func (h *History) Range(since, until time.Time) []Snapshot {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	start, count := 0, h.next
+	if h.full {
+		start, count = h.next, len(h.snapshots)
+	}
+
+	var snapshots []Snapshot
+	for i := range count {
+		s := h.snapshots[(start+i)%len(h.snapshots)]
+		if s.Time.Before(since) || s.Time.After(until) {
+			continue
+		}
+		snapshots = append(snapshots, s)
+	}
+
+	return snapshots
+}
+
+// Point is a downsampled value of a metric
+type Point struct {
+	Time  time.Time `json:"time"`
+	Min   float64   `json:"min"`
+	Max   float64   `json:"max"`
+	Avg   float64   `json:"avg"`
+	Count int       `json:"count"`
+}
+
+// Downsample aggregates the metric into buckets of step starting at since.
+// With a zero step every snapshot makes up a point on its own.
+func Downsample(snapshots []Snapshot, metric string, since time.Time, step time.Duration) []Point {
+	points := []Point{}
+	for _, s := range snapshots {
+		value, ok := s.Value(metric)
+		if !ok {
+			continue
+		}
+
+		bucket := s.Time
+		if step > 0 {
+			bucket = since.Add(s.Time.Sub(since).Truncate(step))
+		}
+
+		last := len(points) - 1
+		if last < 0 || !points[last].Time.Equal(bucket) {
+			points = append(points, Point{Time: bucket, Min: math.Inf(1), Max: math.Inf(-1)})
+			last++
+		}
+
+		p := &points[last]
+		p.Min = min(p.Min, value)
+		p.Max = max(p.Max, value)
+		p.Avg = (p.Avg*float64(p.Count) + value) / float64(p.Count+1)
+		p.Count++
+	}
+
+	return points
+}
@@ -0,0 +1,79 @@
+/*
+Copyright (c) 2025 Tobias Schäfer. All rights reserved.
+Licensed under the MIT license, see LICENSE in the project root for details.
+*/
+package sampler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tschaefer/rpinfo/vcgencmd"
+)
+
+var epoch = time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func snapshotAt(seconds int, temp float64) Snapshot {
+	return Snapshot{
+		Time:        epoch.Add(time.Duration(seconds) * time.Second),
+		Temperature: vcgencmd.Temperature(temp),
+	}
+}
+
+func Test_HistoryRangeReturnsSnapshotsInOrder(t *testing.T) {
+	h := NewHistory(3)
+	h.Add(snapshotAt(0, 40))
+	h.Add(snapshotAt(1, 41))
+
+	snapshots := h.Range(time.Time{}, epoch.Add(time.Hour))
+	assert.Equal(t, []Snapshot{snapshotAt(0, 40), snapshotAt(1, 41)}, snapshots)
+}
+
+func Test_HistoryOverwritesOldestSnapshot(t *testing.T) {
+	h := NewHistory(3)
+	for i := range 5 {
+		h.Add(snapshotAt(i, float64(40+i)))
+	}
+
+	snapshots := h.Range(time.Time{}, epoch.Add(time.Hour))
+	assert.Equal(t, []Snapshot{snapshotAt(2, 42), snapshotAt(3, 43), snapshotAt(4, 44)}, snapshots)
+}
+
+func Test_HistoryRangeFiltersByTime(t *testing.T) {
+	h := NewHistory(10)
+	for i := range 5 {
+		h.Add(snapshotAt(i, float64(40+i)))
+	}
+
+	snapshots := h.Range(epoch.Add(time.Second), epoch.Add(3*time.Second))
+	assert.Equal(t, []Snapshot{snapshotAt(1, 41), snapshotAt(2, 42), snapshotAt(3, 43)}, snapshots)
+}
+
+func Test_DownsampleAggregatesBuckets(t *testing.T) {
+	snapshots := []Snapshot{
+		snapshotAt(0, 40), snapshotAt(1, 42), snapshotAt(2, 44),
+		snapshotAt(3, 50), snapshotAt(5, 46),
+	}
+
+	points := Downsample(snapshots, "temp", epoch, 3*time.Second)
+	assert.Equal(t, []Point{
+		{Time: epoch, Min: 40, Max: 44, Avg: 42, Count: 3},
+		{Time: epoch.Add(3 * time.Second), Min: 46, Max: 50, Avg: 48, Count: 2},
+	}, points)
+}
+
+func Test_DownsampleWithoutStepReturnsEverySnapshot(t *testing.T) {
+	snapshots := []Snapshot{snapshotAt(0, 40), snapshotAt(1, 42)}
+
+	points := Downsample(snapshots, "temp", epoch, 0)
+	assert.Equal(t, []Point{
+		{Time: epoch, Min: 40, Max: 40, Avg: 40, Count: 1},
+		{Time: epoch.Add(time.Second), Min: 42, Max: 42, Avg: 42, Count: 1},
+	}, points)
+}
+
+func Test_DownsampleReturnsEmptyListIfMetricIsUnknown(t *testing.T) {
+	points := Downsample([]Snapshot{snapshotAt(0, 40)}, "volt.unknown", epoch, 0)
+	assert.Equal(t, []Point{}, points)
+}
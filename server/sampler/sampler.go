@@ -0,0 +1,171 @@
+/*
+Copyright (c) 2025 Tobias Schäfer. All rights reserved.
+Licensed under the MIT license, see LICENSE in the project root for details.
+*/
+package sampler
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tschaefer/rpinfo/vcgencmd"
+)
+
+// Snapshot holds the readings of a single sample
+type Snapshot struct {
+	Time        time.Time                     `json:"time"`
+	Temperature vcgencmd.Temperature          `json:"temp"`
+	Voltages    map[string]vcgencmd.Voltage   `json:"volt"`
+	Clocks      map[string]vcgencmd.Frequency `json:"clock"`
+	Throttled   vcgencmd.Throttled            `json:"throttled"`
+}
+
+// Collect runs the commands of the temperature, voltages, clock and
+// throttled endpoints.
+func Collect(ctx context.Context, e vcgencmd.Exec) (Snapshot, error) {
+	var err error
+	snapshot := Snapshot{
+		Time:     time.Now(),
+		Voltages: make(map[string]vcgencmd.Voltage),
+		Clocks:   make(map[string]vcgencmd.Frequency),
+	}
+
+	if snapshot.Temperature, err = vcgencmd.MeasureTemp(ctx, e); err != nil {
+		return Snapshot{}, err
+	}
+
+	for _, rail := range vcgencmd.Rails {
+		if snapshot.Voltages[rail], err = vcgencmd.MeasureVolts(ctx, e, rail); err != nil {
+			return Snapshot{}, err
+		}
+	}
+
+	for _, clock := range vcgencmd.Clocks {
+		if snapshot.Clocks[clock], err = vcgencmd.MeasureClock(ctx, e, clock); err != nil {
+			return Snapshot{}, err
+		}
+	}
+
+	if snapshot.Throttled, err = vcgencmd.GetThrottled(ctx, e); err != nil {
+		return Snapshot{}, err
+	}
+
+	return snapshot, nil
+}
+
+// Metrics returns the names of all readings, e.g. temp, volt.core,
+// clock.arm, throttled and throttled.under_voltage for a single flag.
+func Metrics() []string {
+	metrics := []string{"temp"}
+	for _, rail := range vcgencmd.Rails {
+		metrics = append(metrics, "volt."+rail)
+	}
+	for _, clock := range vcgencmd.Clocks {
+		metrics = append(metrics, "clock."+clock)
+	}
+	metrics = append(metrics, "throttled")
+	for _, flag := range vcgencmd.ThrottledFlags {
+		metrics = append(metrics, "throttled."+flag.Name)
+	}
+
+	return metrics
+}
+
+// Unit returns the unit of a reading, empty for the throttled bitfield.
+func Unit(metric string) string {
+	switch {
+	case metric == "temp":
+		return "celsius"
+	case strings.HasPrefix(metric, "volt."):
+		return "volt"
+	case strings.HasPrefix(metric, "clock."):
+		return "hertz"
+	default:
+		return ""
+	}
+}
+
+// Value returns a reading by its metric name.
+func (s Snapshot) Value(metric string) (float64, bool) {
+	kind, name, _ := strings.Cut(metric, ".")
+	switch kind {
+	case "temp":
+		return float64(s.Temperature), name == ""
+	case "volt":
+		v, ok := s.Voltages[name]
+		return float64(v), ok
+	case "clock":
+		v, ok := s.Clocks[name]
+		return float64(v), ok
+	case "throttled":
+		if name == "" {
+			return float64(s.Throttled), true
+		}
+		for _, flag := range vcgencmd.ThrottledFlags {
+			if flag.Name == name {
+				return float64(s.Throttled >> flag.Bit & 1), true
+			}
+		}
+		return 0, false
+	default:
+		return 0, false
+	}
+}
+
+// Sampler collects snapshots at a fixed interval into a history.
+type Sampler struct {
+	Cmd      vcgencmd.Exec
+	Interval time.Duration
+	History  *History
+
+	mu     sync.RWMutex
+	latest Snapshot
+}
+
+// Run samples until the context is done. A failed sample is logged and
+// skipped.
+func (s *Sampler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.Interval)
+	defer ticker.Stop()
+
+	for {
+		s.sample(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Sampler) sample(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, s.Interval)
+	defer cancel()
+
+	snapshot, err := Collect(ctx, s.Cmd)
+	if err != nil {
+		slog.Warn(fmt.Sprintf("sampler warn: %v", err))
+		return
+	}
+
+	s.mu.Lock()
+	s.latest = snapshot
+	s.mu.Unlock()
+
+	if s.History != nil {
+		s.History.Add(snapshot)
+	}
+}
+
+// Latest returns the most recent snapshot, false if there is none yet.
+func (s *Sampler) Latest() (Snapshot, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.latest, !s.latest.Time.IsZero()
+}
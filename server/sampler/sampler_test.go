@@ -0,0 +1,132 @@
+/*
+Copyright (c) 2025 Tobias Schäfer. All rights reserved.
+Licensed under the MIT license, see LICENSE in the project root for details.
+*/
+package sampler
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tschaefer/rpinfo/vcgencmd"
+)
+
+type mockRunner struct {
+	fail bool
+}
+
+func (m mockRunner) Run(args ...string) (map[string]string, error) {
+	return m.RunContext(context.Background(), args...)
+}
+
+func (m mockRunner) RunContext(ctx context.Context, args ...string) (map[string]string, error) {
+	if m.fail {
+		return nil, fmt.Errorf("command failed")
+	}
+
+	switch args[0] {
+	case "measure_temp":
+		return map[string]string{"temp": "45.0'C"}, nil
+	case "measure_volts":
+		return map[string]string{"volt": "1.2000V"}, nil
+	case "measure_clock":
+		if args[1] == "arm" {
+			return map[string]string{"frequency(48)": "600000000"}, nil
+		}
+		return map[string]string{"frequency(0)": "0"}, nil
+	case "get_throttled":
+		return map[string]string{"throttled": "0x50000"}, nil
+	default:
+		return nil, fmt.Errorf("unknown command")
+	}
+}
+
+func Test_CollectReturnsSnapshot(t *testing.T) {
+	snapshot, err := Collect(context.Background(), mockRunner{})
+	assert.Nil(t, err)
+	assert.False(t, snapshot.Time.IsZero())
+	assert.Equal(t, vcgencmd.Temperature(45.0), snapshot.Temperature)
+	assert.Len(t, snapshot.Voltages, len(vcgencmd.Rails))
+	assert.Equal(t, vcgencmd.Voltage(1.2), snapshot.Voltages["sdram_p"])
+	assert.Len(t, snapshot.Clocks, len(vcgencmd.Clocks))
+	assert.Equal(t, vcgencmd.Frequency(600000000), snapshot.Clocks["arm"])
+	assert.Equal(t, vcgencmd.Throttled(0x50000), snapshot.Throttled)
+}
+
+func Test_CollectReturnsErrorIfCommandFails(t *testing.T) {
+	_, err := Collect(context.Background(), mockRunner{fail: true})
+	assert.Equal(t, fmt.Errorf("command failed"), err)
+}
+
+func Test_SnapshotValueReturnsReadingByMetric(t *testing.T) {
+	snapshot, _ := Collect(context.Background(), mockRunner{})
+
+	for _, metric := range Metrics() {
+		_, ok := snapshot.Value(metric)
+		assert.True(t, ok, metric)
+	}
+
+	value, _ := snapshot.Value("temp")
+	assert.Equal(t, 45.0, value)
+	value, _ = snapshot.Value("clock.arm")
+	assert.Equal(t, 600000000.0, value)
+	value, _ = snapshot.Value("throttled")
+	assert.Equal(t, 327680.0, value)
+	value, _ = snapshot.Value("throttled.under_voltage_occurred")
+	assert.Equal(t, 1.0, value)
+	value, _ = snapshot.Value("throttled.under_voltage")
+	assert.Equal(t, 0.0, value)
+
+	_, ok := snapshot.Value("volt.unknown")
+	assert.False(t, ok)
+	_, ok = snapshot.Value("temp.core")
+	assert.False(t, ok)
+	_, ok = snapshot.Value("throttled.unknown")
+	assert.False(t, ok)
+}
+
+func Test_UnitReturnsUnitByMetric(t *testing.T) {
+	assert.Equal(t, "celsius", Unit("temp"))
+	assert.Equal(t, "volt", Unit("volt.core"))
+	assert.Equal(t, "hertz", Unit("clock.arm"))
+	assert.Equal(t, "", Unit("throttled"))
+}
+
+func Test_SamplerRunRecordsSnapshots(t *testing.T) {
+	s := &Sampler{Cmd: mockRunner{}, Interval: time.Millisecond, History: NewHistory(10)}
+
+	_, ok := s.Latest()
+	assert.False(t, ok)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		s.Run(ctx)
+		close(done)
+	}()
+
+	assert.Eventually(t, func() bool {
+		return len(s.History.Range(time.Time{}, time.Now())) >= 2
+	}, time.Second, time.Millisecond)
+	cancel()
+	<-done
+
+	latest, ok := s.Latest()
+	assert.True(t, ok)
+	assert.Equal(t, vcgencmd.Temperature(45.0), latest.Temperature)
+}
+
+func Test_SamplerRunSkipsFailedSamples(t *testing.T) {
+	s := &Sampler{Cmd: mockRunner{fail: true}, Interval: time.Millisecond, History: NewHistory(10)}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	s.Run(ctx)
+
+	_, ok := s.Latest()
+	assert.False(t, ok)
+	assert.Empty(t, s.History.Range(time.Time{}, time.Now()))
+}
@@ -5,17 +5,35 @@ Licensed under the MIT license, see LICENSE in the project root for details.
 package server
 
 import (
+	"cmp"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
 	"fmt"
 	"log/slog"
+	"maps"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/tschaefer/rpinfo/server/alert"
 	"github.com/tschaefer/rpinfo/server/assets"
+	"github.com/tschaefer/rpinfo/server/board"
+	"github.com/tschaefer/rpinfo/server/graphite"
 	"github.com/tschaefer/rpinfo/server/handler"
+	"github.com/tschaefer/rpinfo/server/influx"
 	"github.com/tschaefer/rpinfo/server/log"
 	"github.com/tschaefer/rpinfo/server/middleware"
+	"github.com/tschaefer/rpinfo/server/mqtt"
+	"github.com/tschaefer/rpinfo/server/otlp"
+	"github.com/tschaefer/rpinfo/server/remotewrite"
+	"github.com/tschaefer/rpinfo/server/sampler"
+	"github.com/tschaefer/rpinfo/server/snmp"
+	"github.com/tschaefer/rpinfo/server/watcher"
 	"github.com/tschaefer/rpinfo/vcgencmd"
 	"github.com/tschaefer/rpinfo/version"
 )
@@ -29,10 +47,246 @@ type Config struct {
 	Redoc     bool
 	LogFormat string
 	LogLevel  string
+	Backend   string
+	Timeout   time.Duration
+	CacheTTL  time.Duration
+	CacheTTLs map[string]time.Duration
+
+	SampleInterval time.Duration
+	HistorySize    int
+	MaxSubscribers int
+	WatchInterval  time.Duration
+	AlertRules     string
+	MetricsLegacy  bool
+
+	RemoteWriteURL       string
+	RemoteWriteInterval  time.Duration
+	RemoteWriteToken     string
+	RemoteWriteUsername  string
+	RemoteWritePassword  string
+	RemoteWriteQueueSize int
+
+	InfluxMeasurement string
+	InfluxTags        map[string]string
+	InfluxURL         string
+	InfluxOrg         string
+	InfluxBucket      string
+	InfluxToken       string
+	InfluxInterval    time.Duration
+	InfluxBatchSize   int
+
+	MQTTBroker    string
+	MQTTClientID  string
+	MQTTUsername  string
+	MQTTPassword  string
+	MQTTTopic     string
+	MQTTDiscovery string
+	MQTTRetain    bool
+	MQTTInterval  time.Duration
+	MQTTCAFile    string
+	MQTTCertFile  string
+	MQTTKeyFile   string
+
+	OTLPEndpoint string
+	OTLPProtocol string
+	OTLPHeaders  map[string]string
+	OTLPInterval time.Duration
+	OTLPCAFile   string
+	OTLPCertFile string
+	OTLPKeyFile  string
+
+	GraphiteAddress  string
+	GraphiteNetwork  string
+	GraphiteFormat   string
+	GraphitePrefix   string
+	GraphiteInterval time.Duration
+
+	SNMPAddress        string
+	SNMPCommunity      string
+	SNMPUser           string
+	SNMPAuthProtocol   string
+	SNMPAuthPassphrase string
+	SNMPPrivProtocol   string
+	SNMPPrivPassphrase string
 }
 
 func Run(config Config) {
-	Handler := handler.Handle{Cmd: vcgencmd.Cmd{}}
+	var cmd vcgencmd.Exec
+	switch config.Backend {
+	case "vcgencmd":
+		cmd = vcgencmd.Cmd{}
+	case "mailbox":
+		cmd = vcgencmd.Mailbox{Device: vcgencmd.Vcio{Path: "/dev/vcio"}}
+	default:
+		slog.Error(fmt.Sprintf("Unknown backend: %s", config.Backend))
+		os.Exit(1)
+	}
+	// The watcher polls the backend directly, a cached result would hide
+	// short events.
+	var watch *watcher.Watcher
+	if config.WatchInterval > 0 {
+		watch = &watcher.Watcher{
+			Cmd:      cmd,
+			Interval: config.WatchInterval,
+			Events:   watcher.NewEvents(1000),
+		}
+	}
+
+	if config.CacheTTL > 0 || len(config.CacheTTLs) > 0 {
+		cmd = vcgencmd.NewCache(cmd, config.CacheTTL, config.CacheTTLs)
+	}
+	var sample *sampler.Sampler
+	if config.SampleInterval > 0 {
+		sample = &sampler.Sampler{
+			Cmd:      cmd,
+			Interval: config.SampleInterval,
+			History:  sampler.NewHistory(config.HistorySize),
+		}
+	}
+	var alerting *alert.Engine
+	if config.AlertRules != "" {
+		rules, err := alert.Load(config.AlertRules)
+		if err != nil {
+			slog.Error(fmt.Sprintf("Failed to load alert rules: %v", err))
+			os.Exit(1)
+		}
+		alerting = &alert.Engine{Rules: rules.Rules, Sampler: sample}
+		if len(rules.Webhooks) > 0 {
+			alerting.Notifier = alert.NewNotifier(rules.Webhooks)
+		}
+	}
+
+	Handler := handler.Handle{
+		Cmd:           cmd,
+		Timeout:       config.Timeout,
+		Sampler:       sample,
+		Subscribers:   handler.NewLimiter(config.MaxSubscribers),
+		Watcher:       watch,
+		Alerting:      alerting,
+		MetricsLegacy: config.MetricsLegacy,
+		InfluxFormat:  influx.Format{Measurement: config.InfluxMeasurement, Tags: influxTags(config.InfluxTags)},
+		FirmwareHash:  firmwareHash(cmd, config.Timeout),
+	}
+
+	var push *remotewrite.Writer
+	if config.RemoteWriteURL != "" {
+		host, _ := os.Hostname()
+		push = &remotewrite.Writer{
+			URL:      config.RemoteWriteURL,
+			Token:    config.RemoteWriteToken,
+			Username: config.RemoteWriteUsername,
+			Password: config.RemoteWritePassword,
+			Interval: config.RemoteWriteInterval,
+			Labels:   map[string]string{"instance": host, "job": "rpinfo"},
+			Gather:   Handler.Gather,
+			Queue:    remotewrite.NewQueue(config.RemoteWriteQueueSize),
+			Client:   &http.Client{Timeout: 10 * time.Second},
+		}
+	}
+
+	var influxWriter *influx.Writer
+	if config.InfluxURL != "" {
+		influxWriter = &influx.Writer{
+			URL:       config.InfluxURL,
+			Org:       config.InfluxOrg,
+			Bucket:    config.InfluxBucket,
+			Token:     config.InfluxToken,
+			Interval:  config.InfluxInterval,
+			BatchSize: config.InfluxBatchSize,
+			Collect: func(ctx context.Context) ([]byte, error) {
+				snapshot, err := Handler.Snapshot(ctx)
+				if err != nil {
+					return nil, err
+				}
+				return Handler.InfluxFormat.Line(snapshot), nil
+			},
+			Client: &http.Client{Timeout: 10 * time.Second},
+		}
+	}
+
+	var publisher *mqtt.Publisher
+	if config.MQTTBroker != "" {
+		tlsConfig, err := clientTLS(config.MQTTCAFile, config.MQTTCertFile, config.MQTTKeyFile)
+		if err != nil {
+			slog.Error(fmt.Sprintf("Failed to load mqtt tls config: %v", err))
+			os.Exit(1)
+		}
+
+		host, _ := os.Hostname()
+		publisher = &mqtt.Publisher{
+			Broker:    config.MQTTBroker,
+			ClientID:  cmp.Or(config.MQTTClientID, "rpinfo-"+host),
+			Username:  config.MQTTUsername,
+			Password:  config.MQTTPassword,
+			TLS:       tlsConfig,
+			Topic:     cmp.Or(config.MQTTTopic, "rpinfo/"+host),
+			Discovery: config.MQTTDiscovery,
+			Retain:    config.MQTTRetain,
+			Interval:  config.MQTTInterval,
+			Device:    mqtt.Device{Name: host, Model: board.Model(), Serial: board.Serial()},
+			Snapshot:  Handler.Snapshot,
+		}
+	}
+
+	var exporter *otlp.Exporter
+	if config.OTLPEndpoint != "" {
+		tlsConfig, err := clientTLS(config.OTLPCAFile, config.OTLPCertFile, config.OTLPKeyFile)
+		if err != nil {
+			slog.Error(fmt.Sprintf("Failed to load otlp tls config: %v", err))
+			os.Exit(1)
+		}
+
+		host, _ := os.Hostname()
+		exporter = &otlp.Exporter{
+			Endpoint: config.OTLPEndpoint,
+			Protocol: config.OTLPProtocol,
+			Headers:  config.OTLPHeaders,
+			TLS:      tlsConfig,
+			Interval: config.OTLPInterval,
+			Host:     host,
+			Model:    board.Model(),
+			Serial:   board.Serial(),
+			Snapshot: Handler.Snapshot,
+		}
+		if watch != nil {
+			exporter.Events = watch.Events
+		}
+	}
+
+	var emitter *graphite.Emitter
+	if config.GraphiteAddress != "" {
+		host, _ := os.Hostname()
+		emitter = &graphite.Emitter{
+			Address:  config.GraphiteAddress,
+			Network:  config.GraphiteNetwork,
+			Format:   config.GraphiteFormat,
+			Prefix:   graphite.Prefix(config.GraphitePrefix, host),
+			Interval: config.GraphiteInterval,
+			Snapshot: Handler.Snapshot,
+		}
+	}
+
+	var agent *snmp.Agent
+	if config.SNMPAddress != "" {
+		host, _ := os.Hostname()
+		agent = &snmp.Agent{
+			Address:   config.SNMPAddress,
+			Community: config.SNMPCommunity,
+			EngineID:  snmp.EngineID(cmp.Or(board.Serial(), host)),
+			Snapshot:  Handler.Snapshot,
+		}
+		if config.SNMPUser != "" {
+			auth, _ := snmp.ParseAuthProtocol(config.SNMPAuthProtocol)
+			priv, _ := snmp.ParsePrivProtocol(config.SNMPPrivProtocol)
+			agent.User = &snmp.User{
+				Name:           config.SNMPUser,
+				AuthProtocol:   auth,
+				AuthPassphrase: config.SNMPAuthPassphrase,
+				PrivProtocol:   priv,
+				PrivPassphrase: config.SNMPPrivPassphrase,
+			}
+		}
+	}
 
 	router := mux.NewRouter()
 	router.Handle("/temperature", middleware.ApplyAll(config.Auth, config.Token, Handler.Temperature)).Methods(http.MethodGet)
@@ -40,13 +294,35 @@ func Run(config Config) {
 	router.Handle("/voltages", middleware.ApplyAll(config.Auth, config.Token, Handler.Voltages)).Methods(http.MethodGet)
 	router.Handle("/throttled", middleware.ApplyAll(config.Auth, config.Token, Handler.Throttled)).Methods(http.MethodGet)
 	router.Handle("/clock", middleware.ApplyAll(config.Auth, config.Token, Handler.Clock)).Methods(http.MethodGet)
+	router.Handle("/board", middleware.ApplyAll(config.Auth, config.Token, Handler.Board)).Methods(http.MethodGet)
+	router.Handle("/firmware", middleware.ApplyAll(config.Auth, config.Token, Handler.Firmware)).Methods(http.MethodGet)
+	router.Handle("/memory", middleware.ApplyAll(config.Auth, config.Token, Handler.Memory)).Methods(http.MethodGet)
+	router.Handle("/codecs", middleware.ApplyAll(config.Auth, config.Token, Handler.Codecs)).Methods(http.MethodGet)
+
+	// History, stream and websocket serve the samples, they require
+	// --sample-interval.
+	if sample != nil {
+		router.Handle("/history/{metric}", middleware.ApplyAll(config.Auth, config.Token, Handler.History)).Methods(http.MethodGet)
+		router.HandleFunc("/stream", middleware.Authorization(config.Auth, config.Token, Handler.Stream)).Methods(http.MethodGet)
+		router.HandleFunc("/ws", middleware.Authorization(config.Auth, config.Token, Handler.WebSocket)).Methods(http.MethodGet)
+	}
+
+	if watch != nil {
+		router.Handle("/events", middleware.ApplyAll(config.Auth, config.Token, Handler.Events)).Methods(http.MethodGet)
+	}
+
+	if alerting != nil {
+		router.Handle("/alerts", middleware.ApplyAll(config.Auth, config.Token, Handler.Alerts)).Methods(http.MethodGet)
+	}
+
+	router.HandleFunc("/influx", middleware.Authorization(config.Auth, config.Token, Handler.Influx)).Methods(http.MethodGet)
 
 	if config.Redoc {
 		router.PathPrefix("/redoc").Handler(http.StripPrefix("/redoc", http.FileServer(http.FS(assets.StaticContent))))
 	}
 
 	if config.Metrics {
-		router.HandleFunc("/metrics", middleware.Authorization(config.Auth, config.Token, handler.Metrics)).Methods(http.MethodGet)
+		router.HandleFunc("/metrics", middleware.Authorization(config.Auth, config.Token, Handler.Metrics)).Methods(http.MethodGet)
 	}
 
 	router.NotFoundHandler = http.HandlerFunc(handler.NotFoundHandler)
@@ -65,10 +341,120 @@ func Run(config Config) {
 		Handler:        router,
 	}
 
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		shutdown, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		_ = server.Shutdown(shutdown)
+	}()
+
+	if sample != nil {
+		go sample.Run(ctx)
+	}
+	if watch != nil {
+		go watch.Run(ctx)
+	}
+	if alerting != nil {
+		go alerting.Run(ctx)
+	}
+	if push != nil {
+		go push.Run(ctx)
+	}
+	flushed := make(chan struct{})
+	if influxWriter != nil {
+		go func() {
+			defer close(flushed)
+			influxWriter.Run(ctx)
+		}()
+	} else {
+		close(flushed)
+	}
+	if publisher != nil {
+		go publisher.Run(ctx)
+	}
+	if exporter != nil {
+		go exporter.Run(ctx)
+	}
+	if emitter != nil {
+		go emitter.Run(ctx)
+	}
+	if agent != nil {
+		go agent.Run(ctx)
+	}
+
 	slog.Info(fmt.Sprintf("Starting rpinfo server. Version: %s - %s", version.Release(), version.Commit()))
-	slog.Info(fmt.Sprintf("Listening on %s:%s, auth: %t, metrics: %t, redoc: %t", config.Host, config.Port, config.Auth, config.Metrics, config.Redoc))
-	if err := server.ListenAndServe(); err != nil {
+	if rpi := board.Read(); rpi.Model != "" {
+		slog.Info(fmt.Sprintf("Running on %s", rpi))
+	}
+	slog.Info(fmt.Sprintf("Listening on %s:%s, auth: %t, metrics: %t, redoc: %t, backend: %s", config.Host, config.Port, config.Auth, config.Metrics, config.Redoc, config.Backend))
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
 		slog.Error(fmt.Sprintf("Failed to start server: %v", err))
 		os.Exit(1)
 	}
+
+	// Wait for the final write of the buffered InfluxDB points.
+	<-flushed
+	slog.Info("Stopped rpinfo server")
+}
+
+// firmwareHash returns the hash of the running firmware, empty if unknown.
+// The firmware doesn't change at runtime, it is read once.
+func firmwareHash(cmd vcgencmd.Exec, timeout time.Duration) string {
+	ctx := context.Background()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	build, err := vcgencmd.Version(ctx, cmd)
+	if err != nil {
+		slog.Warn(fmt.Sprintf("Failed to read firmware version: %v", err))
+		return ""
+	}
+
+	return build.Hash
+}
+
+// influxTags adds the hostname as host tag unless set.
+func influxTags(tags map[string]string) map[string]string {
+	all := map[string]string{}
+	if host, err := os.Hostname(); err == nil {
+		all["host"] = host
+	}
+	maps.Copy(all, tags)
+
+	return all
+}
+
+// clientTLS returns the TLS config of an outgoing connection, e.g. to the
+// MQTT broker, with an optional CA and client certificate, nil if none is
+// given.
+func clientTLS(caFile, certFile, keyFile string) (*tls.Config, error) {
+	if caFile == "" && certFile == "" {
+		return nil, nil
+	}
+
+	config := &tls.Config{MinVersion: tls.VersionTLS12}
+	if caFile != "" {
+		pem, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, err
+		}
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificate found in %s", caFile)
+		}
+	}
+	if certFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, err
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+
+	return config, nil
 }
@@ -0,0 +1,323 @@
+/*
+Copyright (c) 2025 Tobias Schäfer. All rights reserved.
+Licensed under the MIT license, see LICENSE in the project root for details.
+*/
+package snmp
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/gosnmp/gosnmp"
+	"github.com/tschaefer/rpinfo/server/sampler"
+)
+
+const (
+	usmStatsNotInTimeWindows = ".1.3.6.1.6.3.15.1.1.2.0"
+	usmStatsUnknownEngineIDs = ".1.3.6.1.6.3.15.1.1.4.0"
+
+	// Maximum difference of the engine time of a request, RFC 3414 3.2.7
+	timeWindow = 150
+
+	// A walk issues a request per instance, they share a snapshot.
+	maxAge = time.Second
+)
+
+// User is the SNMPv3 user of the User-based Security Model.
+type User struct {
+	Name           string
+	AuthProtocol   gosnmp.SnmpV3AuthProtocol
+	AuthPassphrase string
+	PrivProtocol   gosnmp.SnmpV3PrivProtocol
+	PrivPassphrase string
+}
+
+// Agent answers read requests for the readings below Root via SNMPv2c with
+// the Community and via SNMPv3 with the User, either is disabled if unset.
+// Set requests are refused, SNMPv1 requests are dropped.
+type Agent struct {
+	Address   string
+	Community string
+	User      *User
+	EngineID  string
+	Snapshot  func(ctx context.Context) (sampler.Snapshot, error)
+
+	boots   uint32
+	started time.Time
+	unknown uint32
+	vars    []variable
+	taken   time.Time
+}
+
+// EngineID returns an engine id of the text format of RFC 3411 with the
+// Net-SNMP enterprise number, e.g. of the board serial.
+func EngineID(name string) string {
+	id := "\x80\x00\x1f\x88\x04" + name
+	return id[:min(len(id), 32)]
+}
+
+// Run listens on the address until the context is done.
+func (a *Agent) Run(ctx context.Context) {
+	conn, err := net.ListenPacket("udp", a.Address)
+	if err != nil {
+		slog.Error(fmt.Sprintf("snmp error: %v", err))
+		return
+	}
+	go func() {
+		<-ctx.Done()
+		conn.Close()
+	}()
+
+	if err := a.Serve(conn); err != nil && ctx.Err() == nil {
+		slog.Error(fmt.Sprintf("snmp error: %v", err))
+	}
+}
+
+// Serve answers the requests read from the connection until it is closed.
+// The engine boots is the start time, it increases with every restart
+// without keeping state.
+func (a *Agent) Serve(conn net.PacketConn) error {
+	a.started = time.Now()
+	a.boots = uint32(a.started.Unix() & 0x7fffffff)
+
+	buf := make([]byte, 65535)
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			return err
+		}
+
+		response, err := a.handle(buf[:n])
+		if err != nil {
+			slog.Warn(fmt.Sprintf("snmp warn: %s: %v", addr, err))
+			continue
+		}
+		if response == nil {
+			continue
+		}
+		if _, err := conn.WriteTo(response, addr); err != nil {
+			slog.Warn(fmt.Sprintf("snmp warn: %s: %v", addr, err))
+		}
+	}
+}
+
+// handle returns the marshalled response of a request, nil if the request
+// is dropped.
+func (a *Agent) handle(msg []byte) ([]byte, error) {
+	decoder := &gosnmp.GoSNMP{Version: gosnmp.Version2c}
+	if a.User != nil {
+		decoder.Version = gosnmp.Version3
+		decoder.SecurityModel = gosnmp.UserSecurityModel
+		decoder.SecurityParameters = &gosnmp.UsmSecurityParameters{
+			AuthoritativeEngineID:    a.EngineID,
+			UserName:                 a.User.Name,
+			AuthenticationProtocol:   a.User.AuthProtocol,
+			AuthenticationPassphrase: a.User.AuthPassphrase,
+			PrivacyProtocol:          a.User.PrivProtocol,
+			PrivacyPassphrase:        a.User.PrivPassphrase,
+		}
+	}
+
+	request, err := decoder.UnmarshalTrap(msg, false)
+	if err != nil {
+		return nil, err
+	}
+
+	switch request.Version {
+	case gosnmp.Version2c:
+		if a.Community == "" || subtle.ConstantTimeCompare([]byte(request.Community), []byte(a.Community)) != 1 {
+			return nil, errors.New("unknown community")
+		}
+	case gosnmp.Version3:
+		if a.User == nil {
+			return nil, errors.New("snmpv3 disabled")
+		}
+		usm, ok := request.SecurityParameters.(*gosnmp.UsmSecurityParameters)
+		if !ok {
+			return nil, errors.New("unsupported security model")
+		}
+		if usm.AuthoritativeEngineID != a.EngineID {
+			a.unknown++
+			return a.report(request, gosnmp.NoAuthNoPriv, usmStatsUnknownEngineIDs, a.unknown)
+		}
+		if usm.UserName != a.User.Name {
+			return nil, fmt.Errorf("unknown user: %s", usm.UserName)
+		}
+		if request.MsgFlags&gosnmp.AuthPriv < a.level() {
+			return nil, fmt.Errorf("unsupported security level of user %s", usm.UserName)
+		}
+		boots, now := a.engineTime()
+		if usm.AuthoritativeEngineBoots != boots || absDiff(usm.AuthoritativeEngineTime, now) > timeWindow {
+			return a.report(request, gosnmp.AuthNoPriv, usmStatsNotInTimeWindows, 1)
+		}
+	default:
+		return nil, nil
+	}
+
+	response := a.response(request)
+	response.PDUType = gosnmp.GetResponse
+
+	switch request.PDUType {
+	case gosnmp.GetRequest, gosnmp.GetNextRequest, gosnmp.GetBulkRequest:
+		vars, err := a.variables()
+		if err != nil {
+			slog.Warn(fmt.Sprintf("snmp warn: %v", err))
+			response.Error = gosnmp.GenErr
+			response.ErrorIndex = 1
+			break
+		}
+		response.Variables = answer(vars, request)
+	case gosnmp.SetRequest:
+		response.Error = gosnmp.NotWritable
+		response.ErrorIndex = 1
+	default:
+		return nil, fmt.Errorf("unsupported pdu type: %v", request.PDUType)
+	}
+
+	return response.MarshalMsg()
+}
+
+// answer returns the variable bindings of a read request.
+func answer(vars []variable, request *gosnmp.SnmpPacket) []gosnmp.SnmpPDU {
+	var pdus []gosnmp.SnmpPDU
+	switch request.PDUType {
+	case gosnmp.GetRequest:
+		for _, pdu := range request.Variables {
+			pdus = append(pdus, get(vars, pdu.Name))
+		}
+	case gosnmp.GetNextRequest:
+		for _, pdu := range request.Variables {
+			pdus = append(pdus, next(vars, pdu.Name))
+		}
+	case gosnmp.GetBulkRequest:
+		nonRepeaters := min(int(request.NonRepeaters), len(request.Variables))
+		for _, pdu := range request.Variables[:nonRepeaters] {
+			pdus = append(pdus, next(vars, pdu.Name))
+		}
+		repeaters := request.Variables[nonRepeaters:]
+		for range min(int(request.MaxRepetitions), len(vars)) {
+			done := true
+			for i, pdu := range repeaters {
+				pdu = next(vars, pdu.Name)
+				repeaters[i] = pdu
+				pdus = append(pdus, pdu)
+				done = done && pdu.Type == gosnmp.EndOfMibView
+			}
+			if done {
+				break
+			}
+		}
+	}
+
+	return pdus
+}
+
+// variables returns the object instances of a snapshot, reused for
+// subsequent requests up to the max age.
+func (a *Agent) variables() ([]variable, error) {
+	if a.vars != nil && time.Since(a.taken) < maxAge {
+		return a.vars, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	snapshot, err := a.Snapshot(ctx)
+	if err != nil {
+		return nil, err
+	}
+	a.vars, a.taken = variables(snapshot), time.Now()
+
+	return a.vars, nil
+}
+
+// response returns a response to the request with the security parameters
+// of the agent.
+func (a *Agent) response(request *gosnmp.SnmpPacket) *gosnmp.SnmpPacket {
+	response := &gosnmp.SnmpPacket{
+		Version:         request.Version,
+		Community:       request.Community,
+		MsgFlags:        request.MsgFlags &^ gosnmp.Reportable,
+		MsgID:           request.MsgID,
+		SecurityModel:   request.SecurityModel,
+		ContextEngineID: request.ContextEngineID,
+		ContextName:     request.ContextName,
+		RequestID:       request.RequestID,
+	}
+
+	if request.Version == gosnmp.Version3 {
+		usm := request.SecurityParameters.Copy().(*gosnmp.UsmSecurityParameters)
+		usm.AuthoritativeEngineID = a.EngineID
+		usm.AuthoritativeEngineBoots, usm.AuthoritativeEngineTime = a.engineTime()
+		usm.AuthenticationParameters = ""
+		usm.PrivacyParameters = make([]byte, 8)
+		_, _ = rand.Read(usm.PrivacyParameters)
+		response.SecurityParameters = usm
+		response.ContextEngineID = a.EngineID
+	}
+
+	return response
+}
+
+// report returns a report of a USM statistics counter, e.g. for the engine
+// discovery of a client.
+func (a *Agent) report(request *gosnmp.SnmpPacket, flags gosnmp.SnmpV3MsgFlags, oid string, count uint32) ([]byte, error) {
+	report := a.response(request)
+	report.MsgFlags = flags
+	report.PDUType = gosnmp.Report
+	report.Variables = []gosnmp.SnmpPDU{{Name: oid, Type: gosnmp.Counter32, Value: count}}
+
+	return report.MarshalMsg()
+}
+
+// level returns the minimum security level of the user.
+func (a *Agent) level() gosnmp.SnmpV3MsgFlags {
+	switch {
+	case a.User.PrivProtocol > gosnmp.NoPriv:
+		return gosnmp.AuthPriv
+	case a.User.AuthProtocol > gosnmp.NoAuth:
+		return gosnmp.AuthNoPriv
+	default:
+		return gosnmp.NoAuthNoPriv
+	}
+}
+
+func (a *Agent) engineTime() (uint32, uint32) {
+	return a.boots, uint32(time.Since(a.started).Seconds())
+}
+
+func absDiff(a, b uint32) uint32 {
+	if a > b {
+		return a - b
+	}
+	return b - a
+}
+
+// ParseAuthProtocol returns the authentication protocol by name, e.g. SHA256.
+func ParseAuthProtocol(name string) (gosnmp.SnmpV3AuthProtocol, error) {
+	for _, p := range []gosnmp.SnmpV3AuthProtocol{gosnmp.NoAuth, gosnmp.MD5, gosnmp.SHA, gosnmp.SHA224, gosnmp.SHA256, gosnmp.SHA384, gosnmp.SHA512} {
+		if strings.EqualFold(p.String(), name) {
+			return p, nil
+		}
+	}
+
+	return 0, fmt.Errorf("unknown auth protocol: %s", name)
+}
+
+// ParsePrivProtocol returns the privacy protocol by name, e.g. AES.
+func ParsePrivProtocol(name string) (gosnmp.SnmpV3PrivProtocol, error) {
+	for _, p := range []gosnmp.SnmpV3PrivProtocol{gosnmp.NoPriv, gosnmp.DES, gosnmp.AES, gosnmp.AES192, gosnmp.AES256, gosnmp.AES192C, gosnmp.AES256C} {
+		if strings.EqualFold(p.String(), name) {
+			return p, nil
+		}
+	}
+
+	return 0, fmt.Errorf("unknown priv protocol: %s", name)
+}
@@ -0,0 +1,184 @@
+/*
+Copyright (c) 2025 Tobias Schäfer. All rights reserved.
+Licensed under the MIT license, see LICENSE in the project root for details.
+*/
+package snmp
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/gosnmp/gosnmp"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tschaefer/rpinfo/server/sampler"
+	"github.com/tschaefer/rpinfo/vcgencmd"
+)
+
+var user = &User{
+	Name:           "rpinfo",
+	AuthProtocol:   gosnmp.SHA256,
+	AuthPassphrase: "authsecret",
+	PrivProtocol:   gosnmp.AES,
+	PrivPassphrase: "privsecret",
+}
+
+func snapshot(ctx context.Context) (sampler.Snapshot, error) {
+	return sampler.Snapshot{
+		Time:        time.Now(),
+		Temperature: 42.8,
+		Voltages:    map[string]vcgencmd.Voltage{"core": 0.85, "sdram_c": 1.1},
+		Clocks:      map[string]vcgencmd.Frequency{"arm": 1500000000},
+		Throttled:   0x50005,
+	}, nil
+}
+
+// serve starts the agent on a local port and returns a client of it.
+func serve(t *testing.T, agent *Agent) *gosnmp.GoSNMP {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	go agent.Serve(conn)
+
+	addr := conn.LocalAddr().(*net.UDPAddr)
+	client := &gosnmp.GoSNMP{
+		Target:    addr.IP.String(),
+		Port:      uint16(addr.Port),
+		Community: "secret",
+		Version:   gosnmp.Version2c,
+		Timeout:   time.Second,
+		Retries:   0,
+		MaxOids:   gosnmp.MaxOids,
+	}
+	assert.NoError(t, client.Connect())
+	t.Cleanup(func() { client.Conn.Close() })
+
+	return client
+}
+
+func Test_AgentGet(t *testing.T) {
+	client := serve(t, &Agent{Community: "secret", Snapshot: snapshot})
+
+	result, err := client.Get([]string{temperature + ".0", voltageEntry + ".3.1", clockEntry + ".3.1", throttled + ".0", throttledFlag + ".4.1", Root + ".9.0"})
+	assert.NoError(t, err)
+
+	assert.Equal(t, 42800, result.Variables[0].Value)
+	assert.Equal(t, 850, result.Variables[1].Value)
+	assert.Equal(t, uint(1500000000), result.Variables[2].Value)
+	assert.Equal(t, uint(0x50005), result.Variables[3].Value)
+	assert.Equal(t, truthTrue, result.Variables[4].Value)
+	assert.Equal(t, gosnmp.NoSuchObject, result.Variables[5].Type)
+}
+
+func Test_AgentWalk(t *testing.T) {
+	client := serve(t, &Agent{Community: "secret", Snapshot: snapshot})
+
+	walked, err := client.WalkAll(Root)
+	assert.NoError(t, err)
+	bulk, err := client.BulkWalkAll(Root)
+	assert.NoError(t, err)
+
+	// temperature, 2 rails, 1 clock, throttled and 3 columns per flag
+	assert.Len(t, walked, 1+2*2+2+1+3*len(vcgencmd.ThrottledFlags))
+	assert.Equal(t, walked, bulk)
+	assert.Equal(t, voltageEntry+".2.1", walked[1].Name)
+	assert.Equal(t, "core", string(walked[1].Value.([]byte)))
+	assert.Equal(t, voltageEntry+".2.2", walked[2].Name)
+	assert.Equal(t, voltageEntry+".3.1", walked[3].Name)
+}
+
+func Test_AgentRefusesSet(t *testing.T) {
+	client := serve(t, &Agent{Community: "secret", Snapshot: snapshot})
+
+	result, err := client.Set([]gosnmp.SnmpPDU{{Name: throttled + ".0", Type: gosnmp.Gauge32, Value: uint32(0)}})
+	assert.NoError(t, err)
+	assert.Equal(t, gosnmp.NotWritable, result.Error)
+}
+
+func Test_AgentReportsSnapshotError(t *testing.T) {
+	client := serve(t, &Agent{Community: "secret", Snapshot: func(ctx context.Context) (sampler.Snapshot, error) {
+		return sampler.Snapshot{}, errors.New("vcgencmd failed")
+	}})
+
+	result, err := client.Get([]string{temperature + ".0"})
+	assert.NoError(t, err)
+	assert.Equal(t, gosnmp.GenErr, result.Error)
+}
+
+func Test_AgentDropsUnknownCommunity(t *testing.T) {
+	client := serve(t, &Agent{Community: "secret", Snapshot: snapshot})
+	client.Community = "public"
+
+	_, err := client.Get([]string{temperature + ".0"})
+	assert.Error(t, err)
+}
+
+func Test_AgentV3(t *testing.T) {
+	client := serve(t, &Agent{User: user, EngineID: EngineID("10000000abcdef01"), Snapshot: snapshot})
+	client.Version = gosnmp.Version3
+	client.SecurityModel = gosnmp.UserSecurityModel
+	client.MsgFlags = gosnmp.AuthPriv
+	client.SecurityParameters = &gosnmp.UsmSecurityParameters{
+		UserName:                 user.Name,
+		AuthenticationProtocol:   user.AuthProtocol,
+		AuthenticationPassphrase: user.AuthPassphrase,
+		PrivacyProtocol:          user.PrivProtocol,
+		PrivacyPassphrase:        user.PrivPassphrase,
+	}
+
+	result, err := client.Get([]string{temperature + ".0"})
+	assert.NoError(t, err)
+	assert.Equal(t, 42800, result.Variables[0].Value)
+
+	walked, err := client.BulkWalkAll(Root)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, walked)
+}
+
+func Test_AgentV3RejectsWrongPassphrase(t *testing.T) {
+	client := serve(t, &Agent{User: user, EngineID: EngineID("10000000abcdef01"), Snapshot: snapshot})
+	client.Version = gosnmp.Version3
+	client.SecurityModel = gosnmp.UserSecurityModel
+	client.MsgFlags = gosnmp.AuthNoPriv
+	client.SecurityParameters = &gosnmp.UsmSecurityParameters{
+		UserName:                 user.Name,
+		AuthenticationProtocol:   user.AuthProtocol,
+		AuthenticationPassphrase: "wrongsecret",
+	}
+
+	_, err := client.Get([]string{temperature + ".0"})
+	assert.Error(t, err)
+}
+
+func Test_AgentV3RejectsLowerSecurityLevel(t *testing.T) {
+	client := serve(t, &Agent{User: user, EngineID: EngineID("10000000abcdef01"), Snapshot: snapshot})
+	client.Version = gosnmp.Version3
+	client.SecurityModel = gosnmp.UserSecurityModel
+	client.MsgFlags = gosnmp.NoAuthNoPriv
+	client.SecurityParameters = &gosnmp.UsmSecurityParameters{UserName: user.Name}
+
+	_, err := client.Get([]string{temperature + ".0"})
+	assert.Error(t, err)
+}
+
+func Test_EngineID(t *testing.T) {
+	assert.Equal(t, "\x80\x00\x1f\x88\x04pi", EngineID("pi"))
+	assert.Len(t, EngineID("a-very-long-hostname-of-the-raspberry-pi"), 32)
+}
+
+func Test_ParseProtocols(t *testing.T) {
+	auth, err := ParseAuthProtocol("sha256")
+	assert.NoError(t, err)
+	assert.Equal(t, gosnmp.SHA256, auth)
+	_, err = ParseAuthProtocol("sha3")
+	assert.EqualError(t, err, "unknown auth protocol: sha3")
+
+	priv, err := ParsePrivProtocol("AES")
+	assert.NoError(t, err)
+	assert.Equal(t, gosnmp.AES, priv)
+	_, err = ParsePrivProtocol("3DES")
+	assert.EqualError(t, err, "unknown priv protocol: 3DES")
+}
@@ -0,0 +1,125 @@
+/*
+Copyright (c) 2025 Tobias Schäfer. All rights reserved.
+Licensed under the MIT license, see LICENSE in the project root for details.
+*/
+package snmp
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gosnmp/gosnmp"
+	"github.com/tschaefer/rpinfo/server/sampler"
+	"github.com/tschaefer/rpinfo/vcgencmd"
+)
+
+// Root is the rpinfoMIB module identity, see contrib/RPINFO-MIB.txt. It is
+// placed below netSnmpPlaypen, the enterprise subtree of Net-SNMP for
+// unregistered private use.
+const Root = ".1.3.6.1.4.1.8072.9999.9999.1"
+
+const (
+	objects       = Root + ".1"
+	temperature   = objects + ".1"
+	voltageEntry  = objects + ".2.1"
+	clockEntry    = objects + ".3.1"
+	throttled     = objects + ".4"
+	throttledFlag = objects + ".5.1"
+)
+
+// TruthValue of SNMPv2-TC
+const (
+	truthTrue  = 1
+	truthFalse = 2
+)
+
+// variable is a readable object instance.
+type variable struct {
+	oid []int
+	pdu gosnmp.SnmpPDU
+}
+
+// variables returns the readings of the snapshot as object instances in
+// lexicographic order. Temperatures and voltages are scaled to milli units,
+// SNMP has no floating point type.
+func variables(snapshot sampler.Snapshot) []variable {
+	var vars []variable
+	add := func(oid string, kind gosnmp.Asn1BER, value any) {
+		vars = append(vars, variable{oid: parse(oid), pdu: gosnmp.SnmpPDU{Name: oid, Type: kind, Value: value}})
+	}
+
+	add(temperature+".0", gosnmp.Integer, int(math.Round(float64(snapshot.Temperature)*1000)))
+
+	for i, rail := range vcgencmd.Rails {
+		if value, ok := snapshot.Voltages[rail]; ok {
+			add(fmt.Sprintf("%s.2.%d", voltageEntry, i+1), gosnmp.OctetString, rail)
+			add(fmt.Sprintf("%s.3.%d", voltageEntry, i+1), gosnmp.Integer, int(math.Round(float64(value)*1000)))
+		}
+	}
+
+	for i, clock := range vcgencmd.Clocks {
+		if value, ok := snapshot.Clocks[clock]; ok {
+			add(fmt.Sprintf("%s.2.%d", clockEntry, i+1), gosnmp.OctetString, clock)
+			add(fmt.Sprintf("%s.3.%d", clockEntry, i+1), gosnmp.Gauge32, uint32(value))
+		}
+	}
+
+	add(throttled+".0", gosnmp.Gauge32, uint32(snapshot.Throttled))
+
+	for i, flag := range vcgencmd.ThrottledFlags {
+		state := truthFalse
+		if snapshot.Throttled>>flag.Bit&1 == 1 {
+			state = truthTrue
+		}
+		add(fmt.Sprintf("%s.2.%d", throttledFlag, i+1), gosnmp.OctetString, flag.Name)
+		add(fmt.Sprintf("%s.3.%d", throttledFlag, i+1), gosnmp.Gauge32, uint32(flag.Bit))
+		add(fmt.Sprintf("%s.4.%d", throttledFlag, i+1), gosnmp.Integer, state)
+	}
+
+	slices.SortFunc(vars, func(a, b variable) int { return slices.Compare(a.oid, b.oid) })
+
+	return vars
+}
+
+// parse returns the sub-identifiers of a dotted OID, an invalid one sorts
+// before every object.
+func parse(oid string) []int {
+	var ids []int
+	for part := range strings.SplitSeq(strings.TrimPrefix(oid, "."), ".") {
+		id, err := strconv.Atoi(part)
+		if err != nil {
+			return nil
+		}
+		ids = append(ids, id)
+	}
+
+	return ids
+}
+
+// get returns the instance of the OID, noSuchObject if there is none.
+func get(vars []variable, oid string) gosnmp.SnmpPDU {
+	want := parse(oid)
+	for _, v := range vars {
+		if slices.Equal(v.oid, want) {
+			return v.pdu
+		}
+	}
+
+	return gosnmp.SnmpPDU{Name: oid, Type: gosnmp.NoSuchObject}
+}
+
+// next returns the first instance following the OID, endOfMibView if there
+// is none.
+func next(vars []variable, oid string) gosnmp.SnmpPDU {
+	want := parse(oid)
+	for _, v := range vars {
+		if slices.Compare(v.oid, want) > 0 {
+			return v.pdu
+		}
+	}
+
+	return gosnmp.SnmpPDU{Name: oid, Type: gosnmp.EndOfMibView}
+}
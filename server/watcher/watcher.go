@@ -0,0 +1,174 @@
+/*
+Copyright (c) 2025 Tobias Schäfer. All rights reserved.
+Licensed under the MIT license, see LICENSE in the project root for details.
+*/
+package watcher
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/tschaefer/rpinfo/server/log"
+	"github.com/tschaefer/rpinfo/vcgencmd"
+)
+
+// Event is a single transition of a throttled flag
+type Event struct {
+	Time time.Time `json:"time"`
+	Bit  uint      `json:"bit"`
+	Flag string    `json:"flag"`
+	Set  bool      `json:"set"`
+}
+
+// Transitions returns an event for every flag that differs between the
+// previous and the current bitfield.
+func Transitions(prev, cur vcgencmd.Throttled, t time.Time) []Event {
+	var events []Event
+	for _, flag := range vcgencmd.ThrottledFlags {
+		mask := vcgencmd.Throttled(1) << flag.Bit
+		if prev&mask == cur&mask {
+			continue
+		}
+		events = append(events, Event{Time: t, Bit: flag.Bit, Flag: flag.Desc, Set: cur.Has(mask)})
+	}
+
+	return events
+}
+
+// Events keeps the most recent events up to a fixed size and counts all
+// events ever added per flag and direction.
+type Events struct {
+	mu      sync.RWMutex
+	size    int
+	events  []Event
+	counts  map[uint][2]counter
+	created time.Time
+}
+
+// counter counts the events of a flag in one direction
+type counter struct {
+	count uint64
+	last  time.Time
+}
+
+func NewEvents(size int) *Events {
+	return &Events{size: size, counts: make(map[uint][2]counter), created: time.Now()}
+}
+
+func (e *Events) Add(events ...Event) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for _, event := range events {
+		counts := e.counts[event.Bit]
+		c := &counts[0]
+		if event.Set {
+			c = &counts[1]
+		}
+		c.count++
+		c.last = event.Time
+		e.counts[event.Bit] = counts
+	}
+
+	e.events = append(e.events, events...)
+	if len(e.events) > e.size {
+		e.events = append([]Event(nil), e.events[len(e.events)-e.size:]...)
+	}
+}
+
+// Since returns the events after t in chronological order.
+func (e *Events) Since(t time.Time) []Event {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	events := []Event{}
+	for _, event := range e.events {
+		if event.Time.After(t) {
+			events = append(events, event)
+		}
+	}
+
+	return events
+}
+
+// Count returns the number of events of a flag that has been set or cleared.
+func (e *Events) Count(bit uint, set bool) uint64 {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	return e.counter(bit, set).count
+}
+
+// Last returns the time of the latest event of a flag that has been set or
+// cleared, zero if there was none.
+func (e *Events) Last(bit uint, set bool) time.Time {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	return e.counter(bit, set).last
+}
+
+// Created returns the time the counting started.
+func (e *Events) Created() time.Time {
+	return e.created
+}
+
+func (e *Events) counter(bit uint, set bool) counter {
+	if set {
+		return e.counts[bit][1]
+	}
+	return e.counts[bit][0]
+}
+
+// Watcher polls get_throttled to catch short events between regular polls.
+// The first poll is the baseline and does not produce any event.
+type Watcher struct {
+	Cmd      vcgencmd.Exec
+	Interval time.Duration
+	Events   *Events
+
+	last     vcgencmd.Throttled
+	baseline bool
+}
+
+// Run polls until the context is done. A failed poll is logged and skipped.
+func (w *Watcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.Interval)
+	defer ticker.Stop()
+
+	for {
+		w.poll(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (w *Watcher) poll(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, max(w.Interval, time.Second))
+	defer cancel()
+
+	cur, err := vcgencmd.GetThrottled(ctx, w.Cmd)
+	if err != nil {
+		slog.Warn(fmt.Sprintf("watcher warn: %v", err))
+		return
+	}
+
+	if !w.baseline {
+		w.last, w.baseline = cur, true
+		return
+	}
+
+	events := Transitions(w.last, cur, time.Now())
+	w.last = cur
+	for _, event := range events {
+		log.Throttled(event.Time, event.Bit, event.Flag, event.Set)
+	}
+	w.Events.Add(events...)
+}
@@ -0,0 +1,124 @@
+/*
+Copyright (c) 2025 Tobias Schäfer. All rights reserved.
+Licensed under the MIT license, see LICENSE in the project root for details.
+*/
+package watcher
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tschaefer/rpinfo/vcgencmd"
+)
+
+// mockRunner replies the throttled values in order, the last one repeated
+type mockRunner struct {
+	mu     sync.Mutex
+	values []string
+}
+
+func (m *mockRunner) Run(args ...string) (map[string]string, error) {
+	return m.RunContext(context.Background(), args...)
+}
+
+func (m *mockRunner) RunContext(ctx context.Context, args ...string) (map[string]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	value := m.values[0]
+	if len(m.values) > 1 {
+		m.values = m.values[1:]
+	}
+	if value == "" {
+		return nil, fmt.Errorf("command failed")
+	}
+
+	return map[string]string{"throttled": value}, nil
+}
+
+var epoch = time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func Test_TransitionsReturnsSetAndClearedFlags(t *testing.T) {
+	prev := vcgencmd.Throttled(0x0)
+	cur := vcgencmd.UnderVoltage | vcgencmd.UnderVoltageOccurred
+
+	events := Transitions(prev, cur, epoch)
+	assert.Equal(t, []Event{
+		{Time: epoch, Bit: 0, Flag: "Undervoltage detected", Set: true},
+		{Time: epoch, Bit: 16, Flag: "Undervoltage has occurred", Set: true},
+	}, events)
+
+	events = Transitions(cur, vcgencmd.UnderVoltageOccurred, epoch)
+	assert.Equal(t, []Event{
+		{Time: epoch, Bit: 0, Flag: "Undervoltage detected", Set: false},
+	}, events)
+
+	assert.Empty(t, Transitions(cur, cur, epoch))
+}
+
+func Test_EventsKeepsMostRecent(t *testing.T) {
+	e := NewEvents(2)
+	for i := range 3 {
+		e.Add(Event{Time: epoch.Add(time.Duration(i) * time.Second), Bit: uint(i)})
+	}
+
+	events := e.Since(time.Time{})
+	assert.Len(t, events, 2)
+	assert.Equal(t, uint(1), events[0].Bit)
+	assert.Equal(t, uint(2), events[1].Bit)
+
+	events = e.Since(epoch.Add(time.Second))
+	assert.Len(t, events, 1)
+	assert.Equal(t, uint(2), events[0].Bit)
+}
+
+func Test_EventsCountsAllEvents(t *testing.T) {
+	e := NewEvents(1)
+	e.Add(
+		Event{Time: epoch, Bit: 0, Set: true},
+		Event{Time: epoch, Bit: 0, Set: false},
+		Event{Time: epoch, Bit: 0, Set: true},
+		Event{Time: epoch, Bit: 16, Set: true},
+	)
+
+	assert.Equal(t, uint64(2), e.Count(0, true))
+	assert.Equal(t, uint64(1), e.Count(0, false))
+	assert.Equal(t, uint64(1), e.Count(16, true))
+	assert.Equal(t, uint64(0), e.Count(2, true))
+
+	assert.Equal(t, epoch, e.Last(0, true))
+	assert.True(t, e.Last(2, true).IsZero())
+	assert.False(t, e.Created().IsZero())
+}
+
+func Test_WatcherRecordsTransitions(t *testing.T) {
+	cmd := &mockRunner{values: []string{"0x50000", "0x50005", "", "0x50000"}}
+	w := &Watcher{Cmd: cmd, Interval: time.Millisecond, Events: NewEvents(10)}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		w.Run(ctx)
+		close(done)
+	}()
+
+	assert.Eventually(t, func() bool {
+		return len(w.Events.Since(time.Time{})) == 4
+	}, time.Second, time.Millisecond)
+	cancel()
+	<-done
+
+	events := w.Events.Since(time.Time{})
+	assert.Equal(t, "Undervoltage detected", events[0].Flag)
+	assert.True(t, events[0].Set)
+	assert.Equal(t, "Currently throttled", events[1].Flag)
+	assert.True(t, events[1].Set)
+	assert.Equal(t, "Undervoltage detected", events[2].Flag)
+	assert.False(t, events[2].Set)
+	assert.Equal(t, "Currently throttled", events[3].Flag)
+	assert.False(t, events[3].Set)
+}
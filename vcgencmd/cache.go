@@ -0,0 +1,174 @@
+/*
+Copyright (c) 2025 Tobias Schäfer. All rights reserved.
+Licensed under the MIT license, see LICENSE in the project root for details.
+*/
+package vcgencmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Cached is implemented by an Exec serving results which may have been
+// sampled before the call. The age is the time since the command ran.
+type Cached interface {
+	RunAge(ctx context.Context, args ...string) (map[string]string, time.Duration, error)
+	RunRawAge(ctx context.Context, args ...string) (string, time.Duration, error)
+}
+
+// Cache shares command results for a time to live. Concurrent identical
+// commands are run only once, the callers wait for the same result. Failed
+// commands are not cached.
+type Cache struct {
+	exec Exec
+	ttl  time.Duration
+	ttls map[string]time.Duration
+
+	mu      sync.Mutex
+	entries map[string]*cacheEntry
+	now     func() time.Time
+	// Ceiling of a shared run, see do
+	maxRun time.Duration
+}
+
+// maxRun bounds a shared run of a caller without deadline, a hung command
+// would block every later caller of the command otherwise.
+const maxRun = 30 * time.Second
+
+type cacheEntry struct {
+	done    chan struct{}
+	out     map[string]string
+	raw     string
+	err     error
+	sampled time.Time
+}
+
+// NewCache wraps exec with a default time to live and optional overrides
+// keyed by command name, e.g. get_config.
+func NewCache(exec Exec, ttl time.Duration, ttls map[string]time.Duration) *Cache {
+	return &Cache{
+		exec:    exec,
+		ttl:     ttl,
+		ttls:    ttls,
+		entries: make(map[string]*cacheEntry),
+		now:     time.Now,
+		maxRun:  maxRun,
+	}
+}
+
+func (c *Cache) Run(args ...string) (map[string]string, error) {
+	return c.RunContext(context.Background(), args...)
+}
+
+func (c *Cache) RunContext(ctx context.Context, args ...string) (map[string]string, error) {
+	out, _, err := c.RunAge(ctx, args...)
+	return out, err
+}
+
+// The command runs detached from the caller, see do.
+func (c *Cache) RunAge(ctx context.Context, args ...string) (map[string]string, time.Duration, error) {
+	entry, err := c.do(ctx, strings.Join(args, " "), args, func(ctx context.Context, entry *cacheEntry) {
+		entry.out, entry.err = c.exec.RunContext(ctx, args...)
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return maps.Clone(entry.out), c.now().Sub(entry.sampled), nil
+}
+
+// RunRaw runs the command with the wrapped Exec if it supports raw output,
+// the output is cached apart from the parsed one.
+func (c *Cache) RunRaw(ctx context.Context, args ...string) (string, error) {
+	out, _, err := c.RunRawAge(ctx, args...)
+	return out, err
+}
+
+// The command runs detached from the caller, see do.
+func (c *Cache) RunRawAge(ctx context.Context, args ...string) (string, time.Duration, error) {
+	raw, ok := c.exec.(Raw)
+	if !ok {
+		return "", 0, errors.New("vcgencmd error: raw output not supported")
+	}
+
+	entry, err := c.do(ctx, "raw "+strings.Join(args, " "), args, func(ctx context.Context, entry *cacheEntry) {
+		entry.raw, entry.err = raw.RunRaw(ctx, args...)
+	})
+	if err != nil {
+		return "", 0, err
+	}
+
+	return entry.raw, c.now().Sub(entry.sampled), nil
+}
+
+// do returns the entry of the key, run fills in a new one if there is none
+// or it is expired. The command runs detached from the cancellation of the
+// first caller but bounded by its deadline, e.g. the handle timeout, and
+// always by maxRun, so a disconnecting client doesn't fail the waiting ones.
+// Every caller returns early on its own context.
+func (c *Cache) do(ctx context.Context, key string, args []string, run func(ctx context.Context, entry *cacheEntry)) (*cacheEntry, error) {
+	c.mu.Lock()
+	entry, ok := c.entries[key]
+	if !ok || c.expired(entry, args) {
+		entry = &cacheEntry{done: make(chan struct{})}
+		c.entries[key] = entry
+		c.mu.Unlock()
+
+		deadline := time.Now().Add(c.maxRun)
+		if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+			deadline = d
+		}
+		detached, cancel := context.WithDeadline(context.WithoutCancel(ctx), deadline)
+		go func() {
+			defer cancel()
+			run(detached, entry)
+			entry.sampled = c.now()
+			close(entry.done)
+
+			if entry.err != nil {
+				c.mu.Lock()
+				if c.entries[key] == entry {
+					delete(c.entries, key)
+				}
+				c.mu.Unlock()
+			}
+		}()
+	} else {
+		c.mu.Unlock()
+	}
+
+	select {
+	case <-ctx.Done():
+		return nil, fmt.Errorf("vcgencmd error: %w", ctx.Err())
+	case <-entry.done:
+	}
+
+	if entry.err != nil {
+		return nil, entry.err
+	}
+
+	return entry, nil
+}
+
+// Must be called with the lock held. Entries in flight never expire.
+func (c *Cache) expired(entry *cacheEntry, args []string) bool {
+	select {
+	case <-entry.done:
+	default:
+		return false
+	}
+
+	ttl := c.ttl
+	if len(args) > 0 {
+		if t, ok := c.ttls[args[0]]; ok {
+			ttl = t
+		}
+	}
+
+	return entry.err != nil || c.now().Sub(entry.sampled) >= ttl
+}
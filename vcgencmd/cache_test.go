@@ -0,0 +1,267 @@
+/*
+Copyright (c) 2025 Tobias Schäfer. All rights reserved.
+Licensed under the MIT license, see LICENSE in the project root for details.
+*/
+package vcgencmd
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type countingExec struct {
+	calls atomic.Int32
+	gate  chan struct{}
+	err   error
+}
+
+func (c *countingExec) Run(args ...string) (map[string]string, error) {
+	return c.RunContext(context.Background(), args...)
+}
+
+func (c *countingExec) RunContext(ctx context.Context, args ...string) (map[string]string, error) {
+	n := c.calls.Add(1)
+	if c.gate != nil {
+		<-c.gate
+	}
+	if c.err != nil {
+		return nil, c.err
+	}
+
+	return map[string]string{"call": fmt.Sprint(n)}, nil
+}
+
+// rawExec counts the raw calls of the wrapped exec.
+type rawExec struct {
+	countingExec
+}
+
+func (r *rawExec) RunRaw(ctx context.Context, args ...string) (string, error) {
+	n := r.calls.Add(1)
+	return fmt.Sprintf("call %d", n), nil
+}
+
+// hangingExec runs until the context is done.
+type hangingExec struct{}
+
+func (hangingExec) Run(args ...string) (map[string]string, error) {
+	return hangingExec{}.RunContext(context.Background(), args...)
+}
+
+func (hangingExec) RunContext(ctx context.Context, args ...string) (map[string]string, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (f *fakeClock) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+func (f *fakeClock) Advance(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.now = f.now.Add(d)
+}
+
+func Test_CacheServesResultWithinTTL(t *testing.T) {
+	exec := &countingExec{}
+	clock := &fakeClock{now: time.Now()}
+	cache := NewCache(exec, time.Second, nil)
+	cache.now = clock.Now
+
+	out, age, err := cache.RunAge(context.Background(), "measure_temp")
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"call": "1"}, out)
+	assert.Equal(t, time.Duration(0), age)
+
+	clock.Advance(500 * time.Millisecond)
+	out, age, err = cache.RunAge(context.Background(), "measure_temp")
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"call": "1"}, out)
+	assert.Equal(t, 500*time.Millisecond, age)
+
+	clock.Advance(500 * time.Millisecond)
+	out, age, err = cache.RunAge(context.Background(), "measure_temp")
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"call": "2"}, out)
+	assert.Equal(t, time.Duration(0), age)
+}
+
+func Test_CacheKeysByArguments(t *testing.T) {
+	exec := &countingExec{}
+	cache := NewCache(exec, time.Minute, nil)
+
+	arm, _ := cache.Run("measure_clock", "arm")
+	core, _ := cache.Run("measure_clock", "core")
+	assert.Equal(t, map[string]string{"call": "1"}, arm)
+	assert.Equal(t, map[string]string{"call": "2"}, core)
+}
+
+func Test_CacheAppliesCommandTTL(t *testing.T) {
+	exec := &countingExec{}
+	clock := &fakeClock{now: time.Now()}
+	cache := NewCache(exec, time.Minute, map[string]time.Duration{"get_throttled": time.Second})
+	cache.now = clock.Now
+
+	_, _ = cache.Run("get_throttled")
+	_, _ = cache.Run("measure_temp")
+	clock.Advance(2 * time.Second)
+	_, _ = cache.Run("get_throttled")
+	_, _ = cache.Run("measure_temp")
+
+	assert.Equal(t, int32(3), exec.calls.Load())
+}
+
+func Test_CacheDoesNotCacheErrors(t *testing.T) {
+	exec := &countingExec{err: fmt.Errorf("command failed")}
+	cache := NewCache(exec, time.Minute, nil)
+
+	_, err := cache.Run("measure_temp")
+	assert.Equal(t, fmt.Errorf("command failed"), err)
+	_, err = cache.Run("measure_temp")
+	assert.Equal(t, fmt.Errorf("command failed"), err)
+
+	assert.Equal(t, int32(2), exec.calls.Load())
+}
+
+func Test_CacheReturnsCopy(t *testing.T) {
+	exec := &countingExec{}
+	cache := NewCache(exec, time.Minute, nil)
+
+	out, _ := cache.Run("measure_temp")
+	out["call"] = "modified"
+
+	out, _ = cache.Run("measure_temp")
+	assert.Equal(t, map[string]string{"call": "1"}, out)
+}
+
+func Test_CacheDeduplicatesConcurrentCalls(t *testing.T) {
+	exec := &countingExec{gate: make(chan struct{})}
+	cache := NewCache(exec, time.Minute, nil)
+
+	var wg sync.WaitGroup
+	results := make([]map[string]string, 10)
+	for i := range results {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i], _ = cache.Run("measure_temp")
+		}()
+	}
+
+	assert.Eventually(t, func() bool { return exec.calls.Load() == 1 }, time.Second, time.Millisecond)
+	time.Sleep(10 * time.Millisecond)
+	close(exec.gate)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), exec.calls.Load())
+	for _, out := range results {
+		assert.Equal(t, map[string]string{"call": "1"}, out)
+	}
+}
+
+func Test_CacheWaiterReturnsIfContextIsDone(t *testing.T) {
+	exec := &countingExec{gate: make(chan struct{})}
+	defer close(exec.gate)
+	cache := NewCache(exec, time.Minute, nil)
+
+	go func() { _, _ = cache.Run("measure_temp") }()
+	assert.Eventually(t, func() bool { return exec.calls.Load() == 1 }, time.Second, time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := cache.RunContext(ctx, "measure_temp")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func Test_CacheWaiterGetsResultIfFirstCallerIsCancelled(t *testing.T) {
+	exec := &countingExec{gate: make(chan struct{})}
+	cache := NewCache(exec, time.Minute, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	first := make(chan error, 1)
+	go func() {
+		_, err := cache.RunContext(ctx, "measure_temp")
+		first <- err
+	}()
+	assert.Eventually(t, func() bool { return exec.calls.Load() == 1 }, time.Second, time.Millisecond)
+
+	waiter := make(chan map[string]string, 1)
+	go func() {
+		out, _ := cache.Run("measure_temp")
+		waiter <- out
+	}()
+
+	cancel()
+	assert.ErrorIs(t, <-first, context.Canceled)
+	close(exec.gate)
+
+	assert.Equal(t, map[string]string{"call": "1"}, <-waiter)
+	assert.Equal(t, int32(1), exec.calls.Load())
+}
+
+func Test_CacheRunIsBoundedByDeadlineOfFirstCaller(t *testing.T) {
+	cache := NewCache(hangingExec{}, time.Minute, nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := cache.RunContext(ctx, "measure_temp")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	assert.Eventually(t, func() bool {
+		cache.mu.Lock()
+		defer cache.mu.Unlock()
+		return len(cache.entries) == 0
+	}, time.Second, time.Millisecond)
+}
+
+func Test_CacheRunWithoutDeadlineIsBoundedByMaxRun(t *testing.T) {
+	cache := NewCache(hangingExec{}, time.Minute, nil)
+	cache.maxRun = 10 * time.Millisecond
+
+	_, err := cache.RunContext(context.Background(), "measure_temp")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	assert.Eventually(t, func() bool {
+		cache.mu.Lock()
+		defer cache.mu.Unlock()
+		return len(cache.entries) == 0
+	}, time.Second, time.Millisecond)
+}
+
+func Test_CacheServesRawResultApart(t *testing.T) {
+	exec := &rawExec{}
+	cache := NewCache(exec, time.Second, nil)
+
+	out, err := cache.RunRaw(context.Background(), "version")
+	assert.Nil(t, err)
+	assert.Equal(t, "call 1", out)
+
+	out, err = cache.RunRaw(context.Background(), "version")
+	assert.Nil(t, err)
+	assert.Equal(t, "call 1", out)
+
+	parsed, err := cache.Run("version")
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"call": "2"}, parsed)
+}
+
+func Test_CacheRunRawReturnsErrorIfUnsupported(t *testing.T) {
+	cache := NewCache(&countingExec{}, time.Second, nil)
+
+	_, err := cache.RunRaw(context.Background(), "version")
+	assert.EqualError(t, err, "vcgencmd error: raw output not supported")
+}
@@ -0,0 +1,170 @@
+/*
+Copyright (c) 2025 Tobias Schäfer. All rights reserved.
+Licensed under the MIT license, see LICENSE in the project root for details.
+*/
+package vcgencmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Build is a firmware build as reported by version and bootloader_version,
+// e.g. the variant start of the VideoCore firmware start.elf.
+type Build struct {
+	Date    time.Time `json:"date"`
+	Hash    string    `json:"hash"`
+	Variant string    `json:"variant,omitempty"`
+	Flags   []string  `json:"flags,omitempty"`
+}
+
+// Bootloader is the EEPROM bootloader build of a Raspberry Pi 4 or later.
+type Bootloader struct {
+	Build
+	Timestamp    time.Time `json:"timestamp"`
+	UpdateTime   time.Time `json:"update_time"`
+	Capabilities string    `json:"capabilities,omitempty"`
+}
+
+// Version returns the VideoCore firmware build of version, the exec must
+// implement Raw.
+func Version(ctx context.Context, e Exec) (Build, error) {
+	return runParsed[Build](ctx, e, "version")
+}
+
+// BootloaderVersion returns the EEPROM bootloader build of
+// bootloader_version, the exec must implement Raw.
+func BootloaderVersion(ctx context.Context, e Exec) (Bootloader, error) {
+	return runParsed[Bootloader](ctx, e, "bootloader_version")
+}
+
+// BootloaderConfig returns the EEPROM configuration of bootloader_config by
+// section filter, the exec must implement Raw.
+func BootloaderConfig(ctx context.Context, e Exec) (map[string]map[string]string, error) {
+	return runParsed[map[string]map[string]string](ctx, e, "bootloader_config")
+}
+
+var buildDateLayouts = []string{"Jan _2 2006 15:04:05", "2006/01/02 15:04:05"}
+
+// ParseVersion parses the output of version, e.g.
+//
+//	Oct 17 2023 15:39:16
+//	Copyright (c) 2012 Broadcom
+//	version 30aa0d70ab280427ba04ebc718c81d4350b9d394 (clean) (release) (start)
+func ParseVersion(s string) (Build, error) {
+	build, _, err := parseBuild(s)
+	if err != nil {
+		return Build{}, fmt.Errorf("invalid version: %w", err)
+	}
+
+	return build, nil
+}
+
+// ParseBootloaderVersion parses the output of bootloader_version, e.g.
+//
+//	2023/01/11 17:40:52
+//	version 8ba17717fbcedd4c3b6d4bce7e50c7af4155cba9 (release)
+//	timestamp 1673458852
+//	update-time 1676887458
+//	capabilities 0x0000007f
+func ParseBootloaderVersion(s string) (Bootloader, error) {
+	build, fields, err := parseBuild(s)
+	if err != nil {
+		return Bootloader{}, fmt.Errorf("invalid bootloader version: %w", err)
+	}
+
+	bootloader := Bootloader{Build: build, Capabilities: fields["capabilities"]}
+	for name, t := range map[string]*time.Time{"timestamp": &bootloader.Timestamp, "update-time": &bootloader.UpdateTime} {
+		value, ok := fields[name]
+		if !ok {
+			continue
+		}
+		seconds, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return Bootloader{}, fmt.Errorf("invalid bootloader version: %s: %s", name, value)
+		}
+		*t = time.Unix(seconds, 0).UTC()
+	}
+
+	return bootloader, nil
+}
+
+// ParseBootloaderConfig parses the output of bootloader_config, the
+// settings of the EEPROM configuration by section filter, e.g. all or pi4.
+// Settings before the first section belong to all, comments and blank lines
+// are skipped.
+func ParseBootloaderConfig(s string) map[string]map[string]string {
+	config := make(map[string]map[string]string)
+	section := "all"
+	for line := range strings.SplitSeq(s, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section = strings.TrimSpace(line[1 : len(line)-1])
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		if config[section] == nil {
+			config[section] = make(map[string]string)
+		}
+		config[section][strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+
+	return config
+}
+
+// parseBuild parses the build date line, the version line and the
+// remaining "name value" lines returned as fields.
+func parseBuild(s string) (Build, map[string]string, error) {
+	lines := strings.Split(strings.TrimSpace(s), "\n")
+
+	var build Build
+	date := strings.TrimSpace(lines[0])
+	for _, layout := range buildDateLayouts {
+		if t, err := time.Parse(layout, date); err == nil {
+			build.Date = t
+			break
+		}
+	}
+	if build.Date.IsZero() {
+		return Build{}, nil, fmt.Errorf("date: %s", date)
+	}
+
+	fields := make(map[string]string)
+	for _, line := range lines[1:] {
+		name, value, _ := strings.Cut(strings.TrimSpace(line), " ")
+		if name != "version" {
+			fields[name] = strings.TrimSpace(value)
+			continue
+		}
+
+		words := strings.Fields(value)
+		if len(words) == 0 {
+			return Build{}, nil, errors.New("empty version")
+		}
+		build.Hash = words[0]
+		for _, word := range words[1:] {
+			build.Flags = append(build.Flags, strings.Trim(word, "()"))
+		}
+	}
+	if build.Hash == "" {
+		return Build{}, nil, errors.New("missing version")
+	}
+
+	// The firmware names the start file last, the bootloader has none.
+	if n := len(build.Flags); n > 0 && strings.HasPrefix(build.Flags[n-1], "start") {
+		build.Variant = build.Flags[n-1]
+		build.Flags = build.Flags[:n-1]
+	}
+
+	return build, fields, nil
+}
@@ -0,0 +1,94 @@
+/*
+Copyright (c) 2025 Tobias Schäfer. All rights reserved.
+Licensed under the MIT license, see LICENSE in the project root for details.
+*/
+package vcgencmd
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ParseVersion(t *testing.T) {
+	build, err := ParseVersion("Oct 17 2023 15:39:16 \nCopyright (c) 2012 Broadcom\nversion 30aa0d70ab280427ba04ebc718c81d4350b9d394 (clean) (release) (start)\n")
+	assert.NoError(t, err)
+	assert.Equal(t, Build{
+		Date:    time.Date(2023, 10, 17, 15, 39, 16, 0, time.UTC),
+		Hash:    "30aa0d70ab280427ba04ebc718c81d4350b9d394",
+		Variant: "start",
+		Flags:   []string{"clean", "release"},
+	}, build)
+
+	build, err = ParseVersion("Mar  3 2023 10:52:00\nversion 82f3750a65fadae9a38077e3c2e217ad158c8d54 (clean) (release) (start)")
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2023, 3, 3, 10, 52, 0, 0, time.UTC), build.Date)
+}
+
+func Test_ParseVersionReturnsErrorIfInvalid(t *testing.T) {
+	_, err := ParseVersion("error=1 error_msg=\"Command not registered\"")
+	assert.EqualError(t, err, "invalid version: date: error=1 error_msg=\"Command not registered\"")
+
+	_, err = ParseVersion("Oct 17 2023 15:39:16\nCopyright (c) 2012 Broadcom")
+	assert.EqualError(t, err, "invalid version: missing version")
+}
+
+func Test_ParseBootloaderVersion(t *testing.T) {
+	bootloader, err := ParseBootloaderVersion("2023/01/11 17:40:52\nversion 8ba17717fbcedd4c3b6d4bce7e50c7af4155cba9 (release)\ntimestamp 1673458852\nupdate-time 1676887458\ncapabilities 0x0000007f\n")
+	assert.NoError(t, err)
+	assert.Equal(t, Bootloader{
+		Build: Build{
+			Date:  time.Date(2023, 1, 11, 17, 40, 52, 0, time.UTC),
+			Hash:  "8ba17717fbcedd4c3b6d4bce7e50c7af4155cba9",
+			Flags: []string{"release"},
+		},
+		Timestamp:    time.Unix(1673458852, 0).UTC(),
+		UpdateTime:   time.Unix(1676887458, 0).UTC(),
+		Capabilities: "0x0000007f",
+	}, bootloader)
+
+	_, err = ParseBootloaderVersion("2023/01/11 17:40:52\nversion 8ba17717 (release)\ntimestamp never\n")
+	assert.EqualError(t, err, "invalid bootloader version: timestamp: never")
+}
+
+func Test_ParseBootloaderConfig(t *testing.T) {
+	config := ParseBootloaderConfig("BOOT_UART=0\n# comment\n[all]\nWAKE_ON_GPIO=1\n\n[pi4]\nBOOT_ORDER=0xf41\n[none]\n")
+	assert.Equal(t, map[string]map[string]string{
+		"all": {"BOOT_UART": "0", "WAKE_ON_GPIO": "1"},
+		"pi4": {"BOOT_ORDER": "0xf41"},
+	}, config)
+}
+
+func Test_FirmwareCommandsUseRegisteredParsers(t *testing.T) {
+	mailbox := Mailbox{Device: &fakeDevice{responses: map[string]string{
+		"version":            fixture(t, "version.txt"),
+		"bootloader_version": fixture(t, "bootloader_version.txt"),
+		"bootloader_config":  fixture(t, "bootloader_config.txt"),
+	}}}
+	ctx := context.Background()
+
+	build, err := Version(ctx, mailbox)
+	assert.NoError(t, err)
+	assert.Equal(t, "30aa0d70ab280427ba04ebc718c81d4350b9d394", build.Hash)
+
+	bootloader, err := BootloaderVersion(ctx, mailbox)
+	assert.NoError(t, err)
+	assert.Equal(t, "0x0000007f", bootloader.Capabilities)
+
+	config, err := BootloaderConfig(ctx, mailbox)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"BOOT_ORDER": "0xf41"}, config["pi4"])
+
+	parsersMu.RLock()
+	previous := parsers["version"]
+	parsersMu.RUnlock()
+	Register("version", func(out string) (any, error) {
+		return Lines(out), nil
+	})
+	defer Register("version", previous)
+
+	_, err = Version(ctx, mailbox)
+	assert.EqualError(t, err, "vcgencmd error: unexpected []string of version")
+}
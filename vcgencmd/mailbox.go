@@ -0,0 +1,110 @@
+/*
+Copyright (c) 2025 Tobias Schäfer. All rights reserved.
+Licensed under the MIT license, see LICENSE in the project root for details.
+*/
+package vcgencmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"strings"
+)
+
+const (
+	// VideoCore mailbox property tag to run a general command
+	tagGencmd = 0x00030080
+	// Maximum length of command and response string
+	maxString = 1024
+
+	requestProcess  = 0x00000000
+	responseSuccess = 0x80000000
+)
+
+// Device sends a property message to the VideoCore mailbox. The message is
+// updated in place with the firmware response.
+type Device interface {
+	Property(msg []byte) error
+}
+
+// Mailbox runs commands through the VideoCore mailbox property interface
+// instead of forking the vcgencmd binary.
+type Mailbox struct {
+	Device Device
+}
+
+func (m Mailbox) Run(args ...string) (map[string]string, error) {
+	return m.RunContext(context.Background(), args...)
+}
+
+func (m Mailbox) RunContext(ctx context.Context, args ...string) (map[string]string, error) {
+	out, err := m.RunRaw(ctx, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return parse(out), nil
+}
+
+// The ioctl itself can't be interrupted, on cancellation the caller returns
+// early and the pending property call finishes in the background.
+func (m Mailbox) RunRaw(ctx context.Context, args ...string) (string, error) {
+	command := strings.Join(args, " ")
+	if len(command)+1 >= maxString {
+		return "", fmt.Errorf("vcgencmd error: command too long: %d", len(command))
+	}
+
+	msg := gencmdMessage(command)
+	done := make(chan error, 1)
+	go func() {
+		done <- m.Device.Property(msg)
+	}()
+
+	select {
+	case <-ctx.Done():
+		return "", fmt.Errorf("vcgencmd error: %w", ctx.Err())
+	case err := <-done:
+		if err != nil {
+			return "", fmt.Errorf("vcgencmd error: %v", err)
+		}
+	}
+
+	code := binary.NativeEndian.Uint32(msg[4:])
+	if code != responseSuccess {
+		return "", fmt.Errorf("vcgencmd error: mailbox response code %#x", code)
+	}
+
+	out := gencmdResult(msg)
+	status := binary.NativeEndian.Uint32(msg[20:])
+	if status != 0 {
+		return "", rejected(out, fmt.Sprintf("vcgencmd error: %s - status %d", strings.TrimSpace(out), status))
+	}
+
+	return out, nil
+}
+
+// Build a property message as laid out by the firmware: buffer size,
+// request code, tag, value buffer size, request size, error response,
+// value buffer and end tag.
+func gencmdMessage(command string) []byte {
+	size := 6*4 + maxString + 4
+	msg := make([]byte, size)
+
+	binary.NativeEndian.PutUint32(msg[0:], uint32(size))
+	binary.NativeEndian.PutUint32(msg[4:], requestProcess)
+	binary.NativeEndian.PutUint32(msg[8:], tagGencmd)
+	binary.NativeEndian.PutUint32(msg[12:], maxString)
+	copy(msg[24:], command)
+
+	return msg
+}
+
+func gencmdResult(msg []byte) string {
+	value := msg[24 : 24+maxString]
+	if i := bytes.IndexByte(value, 0); i >= 0 {
+		value = value[:i]
+	}
+
+	return string(value)
+}
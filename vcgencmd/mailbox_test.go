@@ -0,0 +1,179 @@
+/*
+Copyright (c) 2025 Tobias Schäfer. All rights reserved.
+Licensed under the MIT license, see LICENSE in the project root for details.
+*/
+package vcgencmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeDevice struct {
+	responses map[string]string
+	status    uint32
+	code      uint32
+	err       error
+	commands  []string
+}
+
+func (f *fakeDevice) Property(msg []byte) error {
+	if f.err != nil {
+		return f.err
+	}
+
+	size := binary.NativeEndian.Uint32(msg[0:])
+	if int(size) != len(msg) {
+		return fmt.Errorf("invalid buffer size: %d", size)
+	}
+	if tag := binary.NativeEndian.Uint32(msg[8:]); tag != tagGencmd {
+		return fmt.Errorf("invalid tag: %#x", tag)
+	}
+
+	value := msg[24 : 24+maxString]
+	command := string(value[:bytes.IndexByte(value, 0)])
+	f.commands = append(f.commands, command)
+
+	clear(value)
+	copy(value, f.responses[command])
+
+	code := f.code
+	if code == 0 {
+		code = responseSuccess
+	}
+	binary.NativeEndian.PutUint32(msg[4:], code)
+	binary.NativeEndian.PutUint32(msg[16:], uint32(len(f.responses[command])))
+	binary.NativeEndian.PutUint32(msg[20:], f.status)
+
+	return nil
+}
+
+func Test_MailboxRunReturnsParsedOutput(t *testing.T) {
+	device := &fakeDevice{responses: map[string]string{
+		"measure_temp":      "temp=45.0'C",
+		"measure_clock arm": "frequency(0)=600000000",
+	}}
+	mailbox := Mailbox{Device: device}
+
+	out, err := mailbox.Run("measure_temp")
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"temp": "45.0'C"}, out)
+
+	out, err = mailbox.Run("measure_clock", "arm")
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"frequency(0)": "600000000"}, out)
+
+	assert.Equal(t, []string{"measure_temp", "measure_clock arm"}, device.commands)
+}
+
+func Test_MailboxRunRawReturnsOutput(t *testing.T) {
+	device := &fakeDevice{responses: map[string]string{
+		"version": "Oct 17 2023 15:39:16\nCopyright (c) 2012 Broadcom\nversion 30aa0d70 (clean) (release) (start)",
+	}}
+	mailbox := Mailbox{Device: device}
+
+	out, err := mailbox.RunRaw(context.Background(), "version")
+	assert.Nil(t, err)
+	assert.Equal(t, device.responses["version"], out)
+}
+
+func Test_MailboxRunReturnsMultiLineOutput(t *testing.T) {
+	device := &fakeDevice{responses: map[string]string{
+		"get_config int": "arm_freq=1200\ncore_freq=400\n",
+	}}
+	mailbox := Mailbox{Device: device}
+
+	out, err := mailbox.Run("get_config", "int")
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"arm_freq": "1200", "core_freq": "400"}, out)
+}
+
+func Test_MailboxRunReturnsErrorIfCommandFails(t *testing.T) {
+	device := &fakeDevice{
+		responses: map[string]string{"foo": `error=1 error_msg="Command not registered"`},
+		status:    1,
+	}
+	mailbox := Mailbox{Device: device}
+
+	out, err := mailbox.Run("foo")
+	assert.Nil(t, out)
+	assert.EqualError(t, err, `vcgencmd error: error=1 error_msg="Command not registered" - status 1`)
+}
+
+func Test_MailboxRunRawMapsFirmwareErrorsToSentinels(t *testing.T) {
+	device := &fakeDevice{
+		responses: map[string]string{"bootloader_version": `error=1 error_msg="Command not registered"`},
+		status:    1,
+	}
+	_, err := Mailbox{Device: device}.RunRaw(context.Background(), "bootloader_version")
+	assert.ErrorIs(t, err, ErrCommandUnsupported)
+	assert.NotErrorIs(t, err, ErrInvalidArguments)
+
+	device = &fakeDevice{
+		responses: map[string]string{"codec_enabled HEVC": `error=2 error_msg="Invalid arguments"`},
+		status:    2,
+	}
+	_, err = Mailbox{Device: device}.RunRaw(context.Background(), "codec_enabled", "HEVC")
+	assert.ErrorIs(t, err, ErrInvalidArguments)
+	assert.NotErrorIs(t, err, ErrCommandUnsupported)
+
+	_, err = Mailbox{Device: &fakeDevice{code: 0x80000001}}.RunRaw(context.Background(), "measure_temp")
+	assert.NotErrorIs(t, err, ErrCommandUnsupported)
+	assert.NotErrorIs(t, err, ErrInvalidArguments)
+}
+
+func Test_MailboxRunReturnsErrorIfResponseCodeIsInvalid(t *testing.T) {
+	device := &fakeDevice{code: 0x80000001}
+	mailbox := Mailbox{Device: device}
+
+	out, err := mailbox.Run("measure_temp")
+	assert.Nil(t, out)
+	assert.Equal(t, fmt.Errorf("vcgencmd error: mailbox response code 0x80000001"), err)
+}
+
+func Test_MailboxRunReturnsErrorIfDeviceFails(t *testing.T) {
+	device := &fakeDevice{err: fmt.Errorf("open /dev/vcio: no such file or directory")}
+	mailbox := Mailbox{Device: device}
+
+	out, err := mailbox.Run("measure_temp")
+	assert.Nil(t, out)
+	assert.Equal(t, fmt.Errorf("vcgencmd error: open /dev/vcio: no such file or directory"), err)
+}
+
+func Test_MailboxRunReturnsErrorIfCommandIsTooLong(t *testing.T) {
+	device := &fakeDevice{}
+	mailbox := Mailbox{Device: device}
+
+	out, err := mailbox.Run(string(make([]byte, maxString)))
+	assert.Nil(t, out)
+	assert.NotNil(t, err)
+	assert.Empty(t, device.commands)
+}
+
+type blockingDevice struct {
+	release chan struct{}
+}
+
+func (b blockingDevice) Property(msg []byte) error {
+	<-b.release
+	return nil
+}
+
+func Test_MailboxRunContextReturnsErrorIfContextExpires(t *testing.T) {
+	device := blockingDevice{release: make(chan struct{})}
+	defer close(device.release)
+	mailbox := Mailbox{Device: device}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	out, err := mailbox.RunContext(ctx, "measure_temp")
+	assert.Nil(t, out)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
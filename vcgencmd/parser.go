@@ -0,0 +1,268 @@
+/*
+Copyright (c) 2025 Tobias Schäfer. All rights reserved.
+Licensed under the MIT license, see LICENSE in the project root for details.
+*/
+package vcgencmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Parser parses the raw output of a command into a typed value.
+type Parser func(out string) (any, error)
+
+var (
+	parsersMu sync.RWMutex
+	parsers   = map[string]Parser{
+		"measure_temp": func(out string) (any, error) {
+			return ParseTemperature(parse(out)["temp"])
+		},
+		"measure_volts": func(out string) (any, error) {
+			return ParseVoltage(FirstValue(parse(out)))
+		},
+		"measure_clock": func(out string) (any, error) {
+			return ParseFrequency(FirstValue(parse(out)))
+		},
+		"get_throttled": func(out string) (any, error) {
+			return ParseThrottled(parse(out)["throttled"])
+		},
+		"get_mem": func(out string) (any, error) {
+			return ParseMemory(FirstValue(parse(out)))
+		},
+		"get_config": func(out string) (any, error) {
+			return parse(out), nil
+		},
+		"version": func(out string) (any, error) {
+			return ParseVersion(out)
+		},
+		"bootloader_version": func(out string) (any, error) {
+			return ParseBootloaderVersion(out)
+		},
+		"bootloader_config": func(out string) (any, error) {
+			return ParseBootloaderConfig(out), nil
+		},
+		"get_camera": func(out string) (any, error) {
+			return ParseCamera(out)
+		},
+		"get_lcd_info": func(out string) (any, error) {
+			return ParseLCDInfo(out)
+		},
+		"otp_dump": func(out string) (any, error) {
+			return ParseOTP(out)
+		},
+		"codec_enabled": func(out string) (any, error) {
+			return ParseCodecEnabled(out)
+		},
+	}
+)
+
+// Register sets the parser of a command, replacing a known one.
+func Register(command string, parser Parser) {
+	parsersMu.Lock()
+	defer parsersMu.Unlock()
+	parsers[command] = parser
+}
+
+// Parse parses the raw output of a command with the parser registered for
+// its name.
+func Parse(command, out string) (any, error) {
+	parsersMu.RLock()
+	parser, ok := parsers[command]
+	parsersMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("vcgencmd error: no parser for %s", command)
+	}
+
+	return parser(out)
+}
+
+// RunParsed runs a command with raw output and parses it, the exec must
+// implement Raw.
+func RunParsed(ctx context.Context, e Exec, args ...string) (any, error) {
+	if len(args) == 0 {
+		return nil, errors.New("vcgencmd error: missing command")
+	}
+
+	out, err := RunRaw(ctx, e, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return Parse(args[0], out)
+}
+
+// runParsed runs a command like RunParsed and returns the parsed value of
+// the type of the registered parser.
+func runParsed[T any](ctx context.Context, e Exec, args ...string) (T, error) {
+	var zero T
+	value, err := RunParsed(ctx, e, args...)
+	if err != nil {
+		return zero, err
+	}
+
+	typed, ok := value.(T)
+	if !ok {
+		return zero, fmt.Errorf("vcgencmd error: unexpected %T of %s", value, args[0])
+	}
+
+	return typed, nil
+}
+
+// RunRaw runs a command with raw output, the exec must implement Raw.
+func RunRaw(ctx context.Context, e Exec, args ...string) (string, error) {
+	raw, ok := e.(Raw)
+	if !ok {
+		return "", errors.New("vcgencmd error: raw output not supported")
+	}
+
+	return raw.RunRaw(ctx, args...)
+}
+
+// RunLines runs a command with raw output and returns the non-empty lines
+// without surrounding whitespace, the exec must implement Raw.
+func RunLines(ctx context.Context, e Exec, args ...string) ([]string, error) {
+	out, err := RunRaw(ctx, e, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return Lines(out), nil
+}
+
+// Lines returns the non-empty lines of an output without surrounding
+// whitespace.
+func Lines(out string) []string {
+	var lines []string
+	for line := range strings.SplitSeq(out, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+
+	return lines
+}
+
+// Camera is the legacy camera stack state of get_camera.
+type Camera struct {
+	Supported           bool `json:"supported"`
+	Detected            int  `json:"detected"`
+	LibcameraInterfaces int  `json:"libcamera_interfaces"`
+}
+
+var cameraField = regexp.MustCompile(`([a-z][a-z ]*)=(\d+)`)
+
+// ParseCamera parses the output of get_camera, e.g.
+// supported=1 detected=0, libcamera interfaces=1
+func ParseCamera(s string) (Camera, error) {
+	var camera Camera
+	supported := false
+	for _, match := range cameraField.FindAllStringSubmatch(s, -1) {
+		value, _ := strconv.Atoi(match[2])
+		switch strings.TrimSpace(match[1]) {
+		case "supported":
+			camera.Supported, supported = value != 0, true
+		case "detected":
+			camera.Detected = value
+		case "libcamera interfaces":
+			camera.LibcameraInterfaces = value
+		}
+	}
+	if !supported {
+		return Camera{}, fmt.Errorf("invalid camera: %q", s)
+	}
+
+	return camera, nil
+}
+
+// LCD is the resolution and color depth of the display of get_lcd_info.
+type LCD struct {
+	Width  int `json:"width"`
+	Height int `json:"height"`
+	Depth  int `json:"depth"`
+}
+
+// ParseLCDInfo parses the output of get_lcd_info, e.g. 720 480 24
+func ParseLCDInfo(s string) (LCD, error) {
+	fields := strings.Fields(s)
+	if len(fields) < 3 {
+		return LCD{}, fmt.Errorf("invalid lcd info: %q", s)
+	}
+
+	var values [3]int
+	for i := range values {
+		value, err := strconv.Atoi(fields[i])
+		if err != nil {
+			return LCD{}, fmt.Errorf("invalid lcd info: %q", s)
+		}
+		values[i] = value
+	}
+
+	return LCD{Width: values[0], Height: values[1], Depth: values[2]}, nil
+}
+
+// OTP is the one-time programmable memory of otp_dump by row.
+type OTP map[int]uint32
+
+// ParseOTP parses the output of otp_dump, a row and hex value per line,
+// e.g. 28:c8e1a0b2
+func ParseOTP(s string) (OTP, error) {
+	otp := make(OTP)
+	for _, line := range Lines(s) {
+		row, value, ok := strings.Cut(line, ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid otp: %q", line)
+		}
+		r, err := strconv.Atoi(row)
+		if err != nil {
+			return nil, fmt.Errorf("invalid otp: %q", line)
+		}
+		v, err := strconv.ParseUint(value, 16, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid otp: %q", line)
+		}
+		otp[r] = uint32(v)
+	}
+
+	return otp, nil
+}
+
+// ParseCodecEnabled parses the output of codec_enabled by codec, e.g.
+// H264=enabled. A firmware error like error=2 is returned as error, see
+// ErrInvalidArguments.
+func ParseCodecEnabled(s string) (map[string]bool, error) {
+	if strings.HasPrefix(strings.TrimSpace(s), "error=") {
+		return nil, rejected(s, "vcgencmd error: "+strings.Join(Lines(s), " "))
+	}
+
+	codecs := make(map[string]bool)
+	for _, line := range Lines(s) {
+		codec, state, _ := strings.Cut(line, "=")
+		enabled, ok := codecState(state)
+		if !ok {
+			return nil, fmt.Errorf("invalid codec state: %q", line)
+		}
+		codecs[codec] = enabled
+	}
+	if len(codecs) == 0 {
+		return nil, fmt.Errorf("invalid codec state: %q", s)
+	}
+
+	return codecs, nil
+}
+
+func codecState(state string) (enabled, ok bool) {
+	switch state {
+	case "enabled":
+		return true, true
+	case "disabled":
+		return false, true
+	default:
+		return false, false
+	}
+}
@@ -0,0 +1,127 @@
+/*
+Copyright (c) 2025 Tobias Schäfer. All rights reserved.
+Licensed under the MIT license, see LICENSE in the project root for details.
+*/
+package vcgencmd
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func fixture(t *testing.T, name string) string {
+	raw, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatalf("failed to read fixture: %v", err)
+	}
+
+	return string(raw)
+}
+
+func Test_ParseKnownCommands(t *testing.T) {
+	tests := []struct {
+		command  string
+		fixture  string
+		expected any
+	}{
+		{"measure_temp", "measure_temp.txt", Temperature(48.7)},
+		{"measure_volts", "measure_volts.txt", Voltage(0.85)},
+		{"measure_clock", "measure_clock.txt", Frequency(1500398464)},
+		{"get_throttled", "get_throttled.txt", Throttled(0x50005)},
+		{"get_mem", "get_mem.txt", uint64(76 << 20)},
+		{"get_config", "get_config.txt", map[string]string{"arm_freq": "1500", "core_freq": "500", "total_mem": "4096"}},
+		{"version", "version.txt", Build{
+			Date:    time.Date(2023, 10, 17, 15, 39, 16, 0, time.UTC),
+			Hash:    "30aa0d70ab280427ba04ebc718c81d4350b9d394",
+			Variant: "start",
+			Flags:   []string{"clean", "release"},
+		}},
+		{"bootloader_version", "bootloader_version.txt", Bootloader{
+			Build: Build{
+				Date:  time.Date(2023, 1, 11, 17, 40, 52, 0, time.UTC),
+				Hash:  "8ba17717fbcedd4c3b6d4bce7e50c7af4155cba9",
+				Flags: []string{"release"},
+			},
+			Timestamp:    time.Unix(1673458852, 0).UTC(),
+			UpdateTime:   time.Unix(1676887458, 0).UTC(),
+			Capabilities: "0x0000007f",
+		}},
+		{"bootloader_config", "bootloader_config.txt", map[string]map[string]string{"all": {"BOOT_UART": "0", "WAKE_ON_GPIO": "1", "POWER_OFF_ON_HALT": "0"}, "pi4": {"BOOT_ORDER": "0xf41"}}},
+		{"get_camera", "get_camera.txt", Camera{Supported: true, Detected: 1}},
+		{"get_camera", "get_camera_libcamera.txt", Camera{LibcameraInterfaces: 1}},
+		{"get_lcd_info", "get_lcd_info.txt", LCD{Width: 720, Height: 480, Depth: 24}},
+		{"otp_dump", "otp_dump.txt", OTP{8: 0, 9: 0, 10: 0, 16: 0x280000, 28: 0xc8e1a0b2, 30: 0xc03114}},
+		{"codec_enabled", "codec_enabled.txt", map[string]bool{"H264": true}},
+		{"codec_enabled", "codec_enabled_mpg2.txt", map[string]bool{"MPG2": false}},
+	}
+
+	for _, tt := range tests {
+		out, err := Parse(tt.command, fixture(t, tt.fixture))
+		assert.NoError(t, err, tt.fixture)
+		assert.Equal(t, tt.expected, out, tt.fixture)
+	}
+}
+
+func Test_ParseReturnsErrorIfInvalid(t *testing.T) {
+	for command, out := range map[string]string{
+		"get_camera":    "error=1",
+		"get_lcd_info":  "720 x 480",
+		"otp_dump":      "28-c8e1a0b2",
+		"codec_enabled": "HEVC=unknown",
+	} {
+		_, err := Parse(command, out)
+		assert.Error(t, err, command)
+	}
+}
+
+func Test_ParseReturnsErrorIfUnknown(t *testing.T) {
+	_, err := Parse("get_rsts", "")
+	assert.EqualError(t, err, "vcgencmd error: no parser for get_rsts")
+}
+
+func Test_RegisterSetsParser(t *testing.T) {
+	Register("get_rsts", func(out string) (any, error) {
+		return Lines(out), nil
+	})
+	defer func() {
+		parsersMu.Lock()
+		delete(parsers, "get_rsts")
+		parsersMu.Unlock()
+	}()
+
+	out, err := Parse("get_rsts", "rsts=0x00001000\n")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"rsts=0x00001000"}, out)
+}
+
+func Test_RunParsedAndRunLines(t *testing.T) {
+	mailbox := Mailbox{Device: &fakeDevice{responses: map[string]string{
+		"get_lcd_info": fixture(t, "get_lcd_info.txt"),
+		"version":      fixture(t, "version.txt"),
+	}}}
+
+	out, err := RunParsed(context.Background(), mailbox, "get_lcd_info")
+	assert.NoError(t, err)
+	assert.Equal(t, LCD{Width: 720, Height: 480, Depth: 24}, out)
+
+	lines, err := RunLines(context.Background(), mailbox, "version")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"Oct 17 2023 15:39:16",
+		"Copyright (c) 2012 Broadcom",
+		"version 30aa0d70ab280427ba04ebc718c81d4350b9d394 (clean) (release) (start)",
+	}, lines)
+}
+
+func Test_RunRawReturnsErrorIfUnsupported(t *testing.T) {
+	_, err := RunParsed(context.Background(), &countingExec{}, "version")
+	assert.EqualError(t, err, "vcgencmd error: raw output not supported")
+
+	_, err = RunLines(context.Background(), &countingExec{}, "version")
+	assert.EqualError(t, err, "vcgencmd error: raw output not supported")
+}
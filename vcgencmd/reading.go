@@ -0,0 +1,177 @@
+/*
+Copyright (c) 2025 Tobias Schäfer. All rights reserved.
+Licensed under the MIT license, see LICENSE in the project root for details.
+*/
+package vcgencmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"iter"
+	"maps"
+	"strconv"
+	"strings"
+)
+
+// Clocks measurable by measure_clock
+var Clocks = []string{
+	"arm", "core", "h264", "isp",
+	"v3d", "uart", "pwm", "emmc",
+	"pixel", "vec", "hdmi", "dpi",
+}
+
+// Rails measurable by measure_volts
+var Rails = []string{"core", "sdram_c", "sdram_i", "sdram_p"}
+
+// Memory split readable by get_mem
+var MemoryAreas = []string{"arm", "gpu"}
+
+// Firmware heaps readable by get_mem, not supported by every firmware
+var FirmwareHeaps = []string{"malloc_total", "reloc_total"}
+
+// Codecs of the hardware video decoder queryable by codec_enabled
+var Codecs = []string{"H264", "MPG2", "WVC1", "MPG4", "MJPG", "WMV9", "HEVC"}
+
+// ErrCodecUnsupported is returned by CodecEnabled for a codec the firmware
+// doesn't know, e.g. of a newer board.
+var ErrCodecUnsupported = errors.New("codec not supported")
+
+// Temperature in degree Celsius
+type Temperature float64
+
+// Voltage in volt
+type Voltage float64
+
+// Frequency in hertz
+type Frequency int64
+
+type unitValue[T any] struct {
+	Value T      `json:"value"`
+	Unit  string `json:"unit"`
+}
+
+func (t Temperature) MarshalJSON() ([]byte, error) {
+	return json.Marshal(unitValue[float64]{float64(t), "celsius"})
+}
+
+func (v Voltage) MarshalJSON() ([]byte, error) {
+	return json.Marshal(unitValue[float64]{float64(v), "volt"})
+}
+
+func (f Frequency) MarshalJSON() ([]byte, error) {
+	return json.Marshal(unitValue[int64]{int64(f), "hertz"})
+}
+
+// ParseTemperature parses a measure_temp value, e.g. 45.0'C
+func ParseTemperature(s string) (Temperature, error) {
+	value, err := strconv.ParseFloat(strings.TrimSuffix(s, "'C"), 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid temperature: %q", s)
+	}
+
+	return Temperature(value), nil
+}
+
+// ParseVoltage parses a measure_volts value, e.g. 1.3500V
+func ParseVoltage(s string) (Voltage, error) {
+	value, err := strconv.ParseFloat(strings.TrimSuffix(s, "V"), 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid voltage: %q", s)
+	}
+
+	return Voltage(value), nil
+}
+
+// ParseFrequency parses a measure_clock value, e.g. 600000000
+func ParseFrequency(s string) (Frequency, error) {
+	value, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid frequency: %q", s)
+	}
+
+	return Frequency(value), nil
+}
+
+// ParseMemory parses a get_mem value in bytes, e.g. 948M
+func ParseMemory(s string) (uint64, error) {
+	number, unit := s, uint64(1)
+	for suffix, size := range map[string]uint64{"K": 1 << 10, "M": 1 << 20, "G": 1 << 30} {
+		if n, ok := strings.CutSuffix(s, suffix); ok {
+			number, unit = n, size
+		}
+	}
+
+	value, err := strconv.ParseUint(number, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid memory: %q", s)
+	}
+
+	return value * unit, nil
+}
+
+// FirstValue returns the value of a single line output whose key varies,
+// e.g. frequency(48)=600000000 of measure_clock.
+func FirstValue(out map[string]string) string {
+	next, stop := iter.Pull(maps.Values(out))
+	defer stop()
+
+	v, _ := next()
+	return v
+}
+
+// MeasureTemp returns the SoC temperature of measure_temp.
+func MeasureTemp(ctx context.Context, e Exec) (Temperature, error) {
+	out, err := e.RunContext(ctx, "measure_temp")
+	if err != nil {
+		return 0, err
+	}
+
+	return ParseTemperature(out["temp"])
+}
+
+// MeasureVolts returns the voltage of a rail of measure_volts.
+func MeasureVolts(ctx context.Context, e Exec, rail string) (Voltage, error) {
+	out, err := e.RunContext(ctx, "measure_volts", rail)
+	if err != nil {
+		return 0, err
+	}
+
+	return ParseVoltage(out["volt"])
+}
+
+// MeasureClock returns the frequency of a clock of measure_clock.
+func MeasureClock(ctx context.Context, e Exec, clock string) (Frequency, error) {
+	out, err := e.RunContext(ctx, "measure_clock", clock)
+	if err != nil {
+		return 0, err
+	}
+
+	return ParseFrequency(FirstValue(out))
+}
+
+// GetMem returns the size of a memory area of get_mem in bytes, the exec
+// must implement Raw.
+func GetMem(ctx context.Context, e Exec, area string) (uint64, error) {
+	return runParsed[uint64](ctx, e, "get_mem", area)
+}
+
+// CodecEnabled returns whether a codec is enabled by codec_enabled, the exec
+// must implement Raw.
+func CodecEnabled(ctx context.Context, e Exec, codec string) (bool, error) {
+	codecs, err := runParsed[map[string]bool](ctx, e, "codec_enabled", codec)
+	if errors.Is(err, ErrInvalidArguments) {
+		return false, fmt.Errorf("%w: %s", ErrCodecUnsupported, codec)
+	}
+	if err != nil {
+		return false, err
+	}
+
+	enabled, ok := codecs[codec]
+	if !ok {
+		return false, fmt.Errorf("%w: %s", ErrCodecUnsupported, codec)
+	}
+
+	return enabled, nil
+}
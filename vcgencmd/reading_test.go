@@ -0,0 +1,160 @@
+/*
+Copyright (c) 2025 Tobias Schäfer. All rights reserved.
+Licensed under the MIT license, see LICENSE in the project root for details.
+*/
+package vcgencmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type mockExec map[string]map[string]string
+
+func (m mockExec) Run(args ...string) (map[string]string, error) {
+	return m.RunContext(context.Background(), args...)
+}
+
+func (m mockExec) RunContext(ctx context.Context, args ...string) (map[string]string, error) {
+	key := fmt.Sprint(args)
+	out, ok := m[key]
+	if !ok {
+		return nil, fmt.Errorf("vcgencmd error: unknown command %s", key)
+	}
+
+	return out, nil
+}
+
+// RunRaw returns the output as key=value lines like the firmware.
+func (m mockExec) RunRaw(ctx context.Context, args ...string) (string, error) {
+	out, err := m.RunContext(ctx, args...)
+	if err != nil {
+		return "", err
+	}
+
+	var lines []string
+	for _, key := range slices.Sorted(maps.Keys(out)) {
+		lines = append(lines, key+"="+out[key])
+	}
+	return strings.Join(lines, "\n") + "\n", nil
+}
+
+func Test_ParseTemperatureReturnsCelsius(t *testing.T) {
+	temp, err := ParseTemperature("45.0'C")
+	assert.Nil(t, err)
+	assert.Equal(t, Temperature(45.0), temp)
+
+	_, err = ParseTemperature("hot")
+	assert.Equal(t, fmt.Errorf(`invalid temperature: "hot"`), err)
+}
+
+func Test_ParseVoltageReturnsVolt(t *testing.T) {
+	volt, err := ParseVoltage("1.3500V")
+	assert.Nil(t, err)
+	assert.Equal(t, Voltage(1.35), volt)
+
+	_, err = ParseVoltage("")
+	assert.Equal(t, fmt.Errorf(`invalid voltage: ""`), err)
+}
+
+func Test_ParseFrequencyReturnsHertz(t *testing.T) {
+	freq, err := ParseFrequency("600000000")
+	assert.Nil(t, err)
+	assert.Equal(t, Frequency(600000000), freq)
+
+	_, err = ParseFrequency("600MHz")
+	assert.Equal(t, fmt.Errorf(`invalid frequency: "600MHz"`), err)
+}
+
+func Test_ParseMemoryReturnsBytes(t *testing.T) {
+	for value, expected := range map[string]uint64{"948M": 948 << 20, "1G": 1 << 30, "512K": 512 << 10, "0": 0} {
+		mem, err := ParseMemory(value)
+		assert.Nil(t, err)
+		assert.Equal(t, expected, mem, value)
+	}
+
+	_, err := ParseMemory("76MB")
+	assert.Equal(t, fmt.Errorf(`invalid memory: "76MB"`), err)
+}
+
+func Test_ReadingsMarshalWithUnits(t *testing.T) {
+	readings := map[string]any{
+		"temp":      Temperature(45.5),
+		"volt":      Voltage(1.2),
+		"freq":      Frequency(250000000),
+		"throttled": Throttled(0x50000),
+	}
+
+	out, err := json.Marshal(readings)
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{
+		"temp": {"value": 45.5, "unit": "celsius"},
+		"volt": {"value": 1.2, "unit": "volt"},
+		"freq": {"value": 250000000, "unit": "hertz"},
+		"throttled": {"value": 327680, "hex": "0x50000"}
+	}`, string(out))
+}
+
+func Test_MeasureReturnsTypedReadings(t *testing.T) {
+	cmd := mockExec{
+		"[measure_temp]":       {"temp": "45.0'C"},
+		"[measure_volts core]": {"volt": "1.3500V"},
+		"[measure_clock arm]":  {"frequency(48)": "600000000"},
+		"[get_throttled]":      {"throttled": "0x0"},
+		"[get_mem gpu]":        {"gpu": "76M"},
+		"[codec_enabled H264]": {"H264": "enabled"},
+		"[codec_enabled MPG2]": {"error": "2", "error_msg": "Invalid arguments"},
+	}
+	ctx := context.Background()
+
+	temp, err := MeasureTemp(ctx, cmd)
+	assert.Nil(t, err)
+	assert.Equal(t, Temperature(45.0), temp)
+
+	volt, err := MeasureVolts(ctx, cmd, "core")
+	assert.Nil(t, err)
+	assert.Equal(t, Voltage(1.35), volt)
+
+	freq, err := MeasureClock(ctx, cmd, "arm")
+	assert.Nil(t, err)
+	assert.Equal(t, Frequency(600000000), freq)
+
+	throttled, err := GetThrottled(ctx, cmd)
+	assert.Nil(t, err)
+	assert.Equal(t, Throttled(0), throttled)
+
+	mem, err := GetMem(ctx, cmd, "gpu")
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(76<<20), mem)
+
+	enabled, err := CodecEnabled(ctx, cmd, "H264")
+	assert.Nil(t, err)
+	assert.True(t, enabled)
+
+	_, err = CodecEnabled(ctx, cmd, "MPG2")
+	assert.ErrorIs(t, err, ErrCodecUnsupported)
+
+	_, err = MeasureVolts(ctx, cmd, "sdram_c")
+	assert.NotNil(t, err)
+}
+
+func Test_CodecEnabledReturnsUnsupportedOnlyForRejectedCodecs(t *testing.T) {
+	mailbox := Mailbox{Device: &fakeDevice{
+		responses: map[string]string{"codec_enabled HEVC": `error=2 error_msg="Invalid arguments"`},
+		status:    2,
+	}}
+
+	_, err := CodecEnabled(context.Background(), mailbox, "HEVC")
+	assert.ErrorIs(t, err, ErrCodecUnsupported)
+
+	_, err = CodecEnabled(context.Background(), Mailbox{Device: &fakeDevice{code: 0x80000001}}, "HEVC")
+	assert.NotErrorIs(t, err, ErrCodecUnsupported)
+	assert.EqualError(t, err, "vcgencmd error: mailbox response code 0x80000001")
+}
@@ -0,0 +1,111 @@
+/*
+Copyright (c) 2025 Tobias Schäfer. All rights reserved.
+Licensed under the MIT license, see LICENSE in the project root for details.
+*/
+package vcgencmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Throttled is the bitfield reported by get_throttled
+type Throttled uint32
+
+const (
+	UnderVoltage Throttled = 1 << iota
+	FrequencyCapped
+	Throttling
+	SoftTempLimit
+)
+
+const (
+	UnderVoltageOccurred Throttled = 1 << (iota + 16)
+	FrequencyCappedOccurred
+	ThrottlingOccurred
+	SoftTempLimitOccurred
+)
+
+// ThrottledFlag describes a single bit of the throttled bitfield
+type ThrottledFlag struct {
+	Bit  uint
+	Name string
+	Desc string
+}
+
+var ThrottledFlags = []ThrottledFlag{
+	{0, "under_voltage", "Undervoltage detected"},
+	{1, "frequency_capped", "Arm frequency capped"},
+	{2, "throttling", "Currently throttled"},
+	{3, "soft_temp_limit", "Soft temperature limit active"},
+	{16, "under_voltage_occurred", "Undervoltage has occurred"},
+	{17, "frequency_capped_occurred", "Arm frequency capping has occurred"},
+	{18, "throttling_occurred", "Throttling has occurred"},
+	{19, "soft_temp_limit_occurred", "Soft temperature limit has occurred"},
+}
+
+func (t Throttled) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Value uint32 `json:"value"`
+		Hex   string `json:"hex"`
+	}{uint32(t), t.String()})
+}
+
+func (t Throttled) String() string {
+	return fmt.Sprintf("%#x", uint32(t))
+}
+
+// Has reports whether all bits of flag are set
+func (t Throttled) Has(flag Throttled) bool {
+	return t&flag == flag
+}
+
+// Flags reports for every known flag whether its bit is set
+func (t Throttled) Flags() map[string]bool {
+	flags := make(map[string]bool, len(ThrottledFlags))
+	for _, flag := range ThrottledFlags {
+		flags[flag.Name] = t.Has(1 << flag.Bit)
+	}
+
+	return flags
+}
+
+// Unknown returns the set bits which are not a known flag
+func (t Throttled) Unknown() []uint {
+	known := Throttled(0)
+	for _, flag := range ThrottledFlags {
+		known |= 1 << flag.Bit
+	}
+
+	bits := []uint{}
+	for bit := uint(0); bit < 32; bit++ {
+		if t&^known&(1<<bit) != 0 {
+			bits = append(bits, bit)
+		}
+	}
+
+	return bits
+}
+
+// ParseThrottled parses a get_throttled value, e.g. 0x50000
+func ParseThrottled(s string) (Throttled, error) {
+	value, err := strconv.ParseUint(strings.TrimPrefix(s, "0x"), 16, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid throttled: %q", s)
+	}
+
+	return Throttled(value), nil
+}
+
+// GetThrottled returns the throttled state of get_throttled.
+func GetThrottled(ctx context.Context, e Exec) (Throttled, error) {
+	out, err := e.RunContext(ctx, "get_throttled")
+	if err != nil {
+		return 0, err
+	}
+
+	return ParseThrottled(out["throttled"])
+}
@@ -0,0 +1,53 @@
+/*
+Copyright (c) 2025 Tobias Schäfer. All rights reserved.
+Licensed under the MIT license, see LICENSE in the project root for details.
+*/
+package vcgencmd
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ParseThrottledReturnsBitfield(t *testing.T) {
+	throttled, err := ParseThrottled("0x50005")
+	assert.Nil(t, err)
+	assert.Equal(t, Throttled(0x50005), throttled)
+	assert.True(t, throttled.Has(UnderVoltage))
+	assert.False(t, throttled.Has(FrequencyCapped))
+	assert.True(t, throttled.Has(Throttling))
+	assert.False(t, throttled.Has(SoftTempLimit))
+	assert.True(t, throttled.Has(UnderVoltageOccurred))
+	assert.False(t, throttled.Has(FrequencyCappedOccurred))
+	assert.True(t, throttled.Has(ThrottlingOccurred))
+	assert.False(t, throttled.Has(SoftTempLimitOccurred))
+
+	_, err = ParseThrottled("0xZZ")
+	assert.Equal(t, fmt.Errorf(`invalid throttled: "0xZZ"`), err)
+}
+
+func Test_ThrottledFlagsMatchBits(t *testing.T) {
+	bits := []Throttled{
+		UnderVoltage, FrequencyCapped, Throttling, SoftTempLimit,
+		UnderVoltageOccurred, FrequencyCappedOccurred, ThrottlingOccurred, SoftTempLimitOccurred,
+	}
+
+	assert.Len(t, ThrottledFlags, len(bits))
+	for i, flag := range ThrottledFlags {
+		assert.Equal(t, bits[i], Throttled(1)<<flag.Bit, flag.Desc)
+	}
+}
+
+func Test_ThrottledFlagsAndUnknownBits(t *testing.T) {
+	throttled := UnderVoltage | ThrottlingOccurred | 1<<8 | 1<<31
+
+	flags := throttled.Flags()
+	assert.Len(t, flags, len(ThrottledFlags))
+	assert.True(t, flags["under_voltage"])
+	assert.True(t, flags["throttling_occurred"])
+	assert.False(t, flags["throttling"])
+	assert.Equal(t, []uint{8, 31}, throttled.Unknown())
+	assert.Equal(t, []uint{}, Throttled(0x50005).Unknown())
+}
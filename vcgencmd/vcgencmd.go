@@ -5,6 +5,8 @@ Licensed under the MIT license, see LICENSE in the project root for details.
 package vcgencmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"os/exec"
@@ -13,23 +15,90 @@ import (
 
 type Exec interface {
 	Run(args ...string) (map[string]string, error)
+	RunContext(ctx context.Context, args ...string) (map[string]string, error)
+}
+
+// Raw is implemented by an Exec returning the output of a command as is,
+// e.g. for commands like version whose output isn't key=value lines.
+type Raw interface {
+	RunRaw(ctx context.Context, args ...string) (string, error)
+}
+
+var (
+	// ErrCommandUnsupported is returned for a command the firmware doesn't
+	// know, e.g. bootloader_version on a board without EEPROM.
+	ErrCommandUnsupported = errors.New("command not registered")
+	// ErrInvalidArguments is returned for arguments the firmware rejects,
+	// e.g. an unknown codec of codec_enabled.
+	ErrInvalidArguments = errors.New("invalid arguments")
+)
+
+// firmwareError is the error of a command the firmware rejected, it wraps
+// the sentinel of the error code if known.
+type firmwareError struct {
+	msg string
+	err error
+}
+
+func (e *firmwareError) Error() string {
+	return e.msg
+}
+
+func (e *firmwareError) Unwrap() error {
+	return e.err
+}
+
+// rejected returns the error msg of a command the firmware rejected with
+// out, e.g. error=1 error_msg="Command not registered".
+func rejected(out string, msg string) error {
+	var err error
+	if fields := strings.Fields(out); len(fields) > 0 {
+		switch fields[0] {
+		case "error=1":
+			err = ErrCommandUnsupported
+		case "error=2":
+			err = ErrInvalidArguments
+		}
+	}
+
+	return &firmwareError{msg: msg, err: err}
 }
 
 type Cmd struct{}
 
 func (r Cmd) Run(args ...string) (map[string]string, error) {
-	execCommand := exec.Command("vcgencmd", args...)
+	return r.RunContext(context.Background(), args...)
+}
+
+func (r Cmd) RunContext(ctx context.Context, args ...string) (map[string]string, error) {
+	out, err := r.RunRaw(ctx, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return parse(out), nil
+}
+
+func (r Cmd) RunRaw(ctx context.Context, args ...string) (string, error) {
+	execCommand := exec.CommandContext(ctx, "vcgencmd", args...)
 	out, err := execCommand.Output()
+	if ctx.Err() != nil {
+		return "", fmt.Errorf("vcgencmd error: %w", ctx.Err())
+	}
 	if err != nil {
 		if out != nil {
-			err = fmt.Errorf("vcgencmd error: %s - %v", strings.TrimSpace(string(out)), err)
+			err = rejected(string(out), fmt.Sprintf("vcgencmd error: %s - %v", strings.TrimSpace(string(out)), err))
 		} else {
 			err = fmt.Errorf("vcgencmd error: %v", err)
 		}
-		return nil, err
+		return "", err
 	}
 
-	output := strings.TrimSpace(string(out))
+	return string(out), nil
+}
+
+func parse(out string) map[string]string {
+	output := strings.TrimSpace(out)
 
 	outputMap := make(map[string]string)
 	for line := range strings.SplitSeq(output, "\n") {
@@ -44,5 +113,5 @@ func (r Cmd) Run(args ...string) (map[string]string, error) {
 		outputMap[key] = value
 	}
 
-	return outputMap, nil
+	return outputMap
 }
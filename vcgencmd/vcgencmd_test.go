@@ -0,0 +1,57 @@
+/*
+Copyright (c) 2025 Tobias Schäfer. All rights reserved.
+Licensed under the MIT license, see LICENSE in the project root for details.
+*/
+package vcgencmd
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_CmdRunContextReturnsErrorIfContextIsDone(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Nanosecond)
+	defer cancel()
+	<-ctx.Done()
+
+	out, err := Cmd{}.RunContext(ctx, "measure_temp")
+	assert.Nil(t, out)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func Test_ParseReturnsKeyValuePairs(t *testing.T) {
+	out := parse("arm_freq=1200\n core_freq = 400 \nno value\n")
+	assert.Equal(t, map[string]string{"arm_freq": "1200", "core_freq": "400"}, out)
+}
+
+func Test_CmdRunReturnsSentinelIfFirmwareRejectsCommand(t *testing.T) {
+	dir := t.TempDir()
+	script := "#!/bin/sh\necho 'error=1 error_msg=\"Command not registered\"'\nexit 1\n"
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "vcgencmd"), []byte(script), 0o755))
+	t.Setenv("PATH", dir)
+
+	out, err := Cmd{}.Run("bootloader_version")
+	assert.Nil(t, out)
+	assert.ErrorIs(t, err, ErrCommandUnsupported)
+	assert.EqualError(t, err, `vcgencmd error: error=1 error_msg="Command not registered" - exit status 1`)
+}
+
+func Test_RejectedWrapsSentinelOfErrorCode(t *testing.T) {
+	err := rejected(`error=1 error_msg="Command not registered"`, "vcgencmd error")
+	assert.ErrorIs(t, err, ErrCommandUnsupported)
+	assert.NotErrorIs(t, err, ErrInvalidArguments)
+
+	err = rejected(`error=2 error_msg="Invalid arguments"`, "vcgencmd error")
+	assert.ErrorIs(t, err, ErrInvalidArguments)
+	assert.NotErrorIs(t, err, ErrCommandUnsupported)
+
+	err = rejected(`error=3 error_msg="Unknown"`, "vcgencmd error")
+	assert.NotErrorIs(t, err, ErrCommandUnsupported)
+	assert.NotErrorIs(t, err, ErrInvalidArguments)
+	assert.EqualError(t, err, "vcgencmd error")
+}
@@ -0,0 +1,40 @@
+/*
+Copyright (c) 2025 Tobias Schäfer. All rights reserved.
+Licensed under the MIT license, see LICENSE in the project root for details.
+*/
+package vcgencmd
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// _IOWR(100, 0, char *)
+const ioctlMboxProperty = 3<<30 | unsafe.Sizeof(uintptr(0))<<16 | 100<<8
+
+// Vcio is the character device of the VideoCore mailbox driver.
+type Vcio struct {
+	Path string
+}
+
+func (v Vcio) Property(msg []byte) error {
+	path := v.Path
+	if path == "" {
+		path = "/dev/vcio"
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, file.Fd(), ioctlMboxProperty, uintptr(unsafe.Pointer(&msg[0])))
+	if errno != 0 {
+		return fmt.Errorf("ioctl %s: %v", path, errno)
+	}
+
+	return nil
+}
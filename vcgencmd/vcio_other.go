@@ -0,0 +1,20 @@
+//go:build !linux
+
+/*
+Copyright (c) 2025 Tobias Schäfer. All rights reserved.
+Licensed under the MIT license, see LICENSE in the project root for details.
+*/
+package vcgencmd
+
+import (
+	"errors"
+)
+
+// Vcio is the character device of the VideoCore mailbox driver.
+type Vcio struct {
+	Path string
+}
+
+func (v Vcio) Property(msg []byte) error {
+	return errors.New("mailbox not supported on this platform")
+}